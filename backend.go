@@ -2,16 +2,53 @@ package artifactory
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	rtAuth "github.com/jfrog/jfrog-client-go/artifactory/auth"
 	rtHttpClient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	rtLog "github.com/jfrog/jfrog-client-go/utils/log"
+
+	rtGroupService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/group"
+	rtPermissionService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/permission"
+	rtProjectService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/project"
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+	rtUserService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/user"
 )
 
+// Minimum age of a WAL entry before it is rolled back, this must be longer
+// than the time taken to create an access token.
+const walRollbackMinAge = 5 * time.Minute
+
 type backend struct {
 	*framework.Backend
+
+	// The Artifactory client is built lazily from the stored config and
+	// reused across requests until the config changes.
+	lock      sync.RWMutex
+	client    *rtHttpClient.ArtifactoryHttpClient
+	rtDetails rtAuth.ArtifactoryDetails
+
+	// Admin privileges of users and groups read by role audits
+	admins adminCache
+
+	// Serialise changes to each role, and role imports, which also hold
+	// the lock of every role they write
+	roleLocks      []*locksutil.LockEntry
+	roleImportLock sync.Mutex
+
+	// Last time each housekeeping task completed
+	housekeepingLock        sync.Mutex
+	lastTidy                time.Time
+	lastIssuanceCleanup     time.Time
+	lastCredentialCheck     time.Time
+	lastAccessTokenRotation time.Time
+	lastRoleHistoryCleanup  time.Time
 }
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -19,11 +56,21 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 	if err := b.Setup(ctx, conf); err != nil {
 		return nil, err
 	}
+
+	// The jfrog client logs through a package level logger shared by every
+	// mount in the process, so it is set once and not named after a mount.
+	jfrogLoggerOnce.Do(func() {
+		rtLog.SetLogger(rtTokenService.NewLogAdapter(b.Logger().ResetNamed("jfrog")))
+	})
+
 	return b, nil
 }
 
+var jfrogLoggerOnce sync.Once
+
 func Backend() *backend {
 	var b backend
+	b.roleLocks = locksutil.CreateLocks()
 	b.Backend = &framework.Backend{
 		PathsSpecial: &logical.Paths{
 			SealWrapStorage: []string{
@@ -33,8 +80,17 @@ func Backend() *backend {
 
 		Paths: []*framework.Path{
 			pathConfig(&b),
+			pathConfigHousekeeping(&b),
+			pathConfigPolicy(&b),
+			pathConfigConstraints(&b),
 			pathListRoles(&b),
+			// Matched before roles/<name>, whose pattern they overlap
+			pathRolesAudit(&b),
+			pathRoleHistory(&b),
+			pathRoleRollback(&b),
 			pathRoles(&b),
+			pathRolesImport(&b),
+			pathRolesExport(&b),
 			pathToken(&b),
 		},
 
@@ -42,23 +98,66 @@ func Backend() *backend {
 			secretAccessToken(&b),
 		},
 
-		BackendType: logical.TypeLogical,
+		InitializeFunc:    b.initialize,
+		PeriodicFunc:      b.periodicFunc,
+		WALRollback:       b.walRollback,
+		WALRollbackMinAge: walRollbackMinAge,
+		Invalidate:        b.invalidate,
+		BackendType:       logical.TypeLogical,
 	}
 
 	return &b
 }
 
+func (b *backend) invalidate(ctx context.Context, key string) {
+	switch key {
+	case "config":
+		b.resetClient()
+	}
+}
+
+// resetClient discards the cached Artifactory client so that the next
+// request builds a new one from the stored config.
+func (b *backend) resetClient() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.client = nil
+	b.rtDetails = nil
+	b.admins.reset()
+}
+
 func (b *backend) rtClient(ctx context.Context, s logical.Storage) (*rtHttpClient.ArtifactoryHttpClient, rtAuth.ArtifactoryDetails, error) {
+	b.lock.RLock()
+	if b.client != nil {
+		client, rtDetails := b.client, b.rtDetails
+		b.lock.RUnlock()
+		return client, rtDetails, nil
+	}
+	b.lock.RUnlock()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// Another request may have built the client while we waited for the lock
+	if b.client != nil {
+		return b.client, b.rtDetails, nil
+	}
+
 	config, err := b.readConfig(ctx, s)
 	if err != nil {
 		return nil, nil, err
 	}
+	if config == nil {
+		return nil, nil, errors.New("artifactory backend has not been configured")
+	}
 
 	rtDetails := rtAuth.NewArtifactoryDetails()
 	rtDetails.SetUrl(config.Address)
 	rtDetails.SetApiKey(config.ApiKey)
 	rtDetails.SetUser(config.Username)
 	rtDetails.SetPassword(config.Password)
+	rtDetails.SetAccessToken(config.AccessToken)
 
 	client, err := rtHttpClient.ArtifactoryClientBuilder().
 		SetInsecureTls(!config.TlsVerify).
@@ -68,5 +167,68 @@ func (b *backend) rtClient(ctx context.Context, s logical.Storage) (*rtHttpClien
 		return nil, nil, fmt.Errorf("Failed to create Artifactory client: %v\n", err)
 	}
 
+	b.client = client
+	b.rtDetails = rtDetails
+
 	return client, rtDetails, nil
 }
+
+func (b *backend) tokenService(ctx context.Context, s logical.Storage) (*rtTokenService.AccessTokenService, error) {
+	client, rtDetails, err := b.rtClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenService := rtTokenService.NewAccessTokenService(client)
+	tokenService.SetArtifactoryDetails(rtDetails)
+	tokenService.SetLogger(b.Logger())
+	return tokenService, nil
+}
+
+func (b *backend) userService(ctx context.Context, s logical.Storage) (*rtUserService.UserService, error) {
+	client, rtDetails, err := b.rtClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	userService := rtUserService.NewUserService(client)
+	userService.SetArtifactoryDetails(rtDetails)
+	userService.SetLogger(b.Logger())
+	return userService, nil
+}
+
+func (b *backend) permissionService(ctx context.Context, s logical.Storage) (*rtPermissionService.PermissionTargetService, error) {
+	client, rtDetails, err := b.rtClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	permissionService := rtPermissionService.NewPermissionTargetService(client)
+	permissionService.SetArtifactoryDetails(rtDetails)
+	permissionService.SetLogger(b.Logger())
+	return permissionService, nil
+}
+
+func (b *backend) groupService(ctx context.Context, s logical.Storage) (*rtGroupService.GroupService, error) {
+	client, rtDetails, err := b.rtClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	groupService := rtGroupService.NewGroupService(client)
+	groupService.SetArtifactoryDetails(rtDetails)
+	groupService.SetLogger(b.Logger())
+	return groupService, nil
+}
+
+func (b *backend) projectService(ctx context.Context, s logical.Storage) (*rtProjectService.ProjectService, error) {
+	client, rtDetails, err := b.rtClient(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	projectService := rtProjectService.NewProjectService(client)
+	projectService.SetArtifactoryDetails(rtDetails)
+	projectService.SetLogger(b.Logger())
+	return projectService, nil
+}
@@ -8,6 +8,7 @@ import (
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/sdk/logical"
+	rtLog "github.com/jfrog/jfrog-client-go/utils/log"
 )
 
 func newBackend(t *testing.T) (logical.Backend, logical.Storage) {
@@ -54,3 +55,63 @@ func assertLogicalResponse(t *testing.T, expectation Expectation, err error, res
 		t.Fatalf("Expected test case to fail with logical error but succeeded: resp:%#v\n", resp)
 	}
 }
+
+func TestBackend_ClientCache(t *testing.T) {
+	lb, storage := newBackend(t)
+	b := lb.(*backend)
+
+	writeConfig := func(address string) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"address": address,
+				"api_key": "abc123",
+			},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+
+	if _, _, err := b.rtClient(context.Background(), storage); err == nil {
+		t.Fatal("Expected client creation to fail without config")
+	}
+
+	writeConfig("https://example.com/artifactory/")
+	client, _, err := b.rtClient(context.Background(), storage)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v\n", err)
+	}
+	cached, _, err := b.rtClient(context.Background(), storage)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v\n", err)
+	}
+	if client != cached {
+		t.Fatal("Expected client to be reused between calls")
+	}
+
+	writeConfig("https://other.example.com/artifactory/")
+	_, rtDetails, err := b.rtClient(context.Background(), storage)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v\n", err)
+	}
+	if rtDetails.GetUrl() != "https://other.example.com/artifactory/" {
+		t.Fatalf("Expected client to be rebuilt after config write, got url: %s\n", rtDetails.GetUrl())
+	}
+
+	b.invalidate(context.Background(), "config")
+	if b.client != nil {
+		t.Fatal("Expected cached client to be cleared on invalidation")
+	}
+}
+
+func TestFactory_SharedLogger(t *testing.T) {
+	newBackend(t)
+	logger := rtLog.Logger
+
+	// Further mounts in the process must not replace the logger
+	newBackend(t)
+	if rtLog.Logger != logger {
+		t.Fatal("Expected the jfrog logger to be set once per process")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credhelper"
+)
+
+const tokenFormat = "docker"
+
+// errCredentialsNotFound is the message Docker expects when a helper has no
+// credentials for a registry.
+var errCredentialsNotFound = errors.New("credentials not found in native keychain")
+
+// credentials is the Docker credential helper representation of a login.
+type credentials struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+type helper struct {
+	source *credhelper.Source
+}
+
+func (h *helper) run(action string, in io.Reader, out io.Writer) error {
+	switch action {
+	case "get":
+		serverURL, err := readServerURL(in)
+		if err != nil {
+			return err
+		}
+		creds, err := h.get(serverURL)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(out).Encode(creds)
+	case "store":
+		// Credentials are issued by Vault, so logins are not stored
+		_, err := ioutil.ReadAll(in)
+		return err
+	case "erase":
+		if _, err := readServerURL(in); err != nil {
+			return err
+		}
+		return h.source.Forget(tokenFormat)
+	case "list":
+		logins, err := h.list()
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(out).Encode(logins)
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+func readServerURL(in io.Reader) (string, error) {
+	raw, err := ioutil.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	serverURL := strings.TrimSpace(string(raw))
+	if serverURL == "" {
+		return "", errors.New("no server URL provided")
+	}
+	return serverURL, nil
+}
+
+func (h *helper) get(serverURL string) (*credentials, error) {
+	auths, err := h.auths()
+	if err != nil {
+		return nil, err
+	}
+
+	auth, ok := auths[registryHost(serverURL)]
+	if !ok {
+		return nil, errCredentialsNotFound
+	}
+
+	return &credentials{
+		ServerURL: serverURL,
+		Username:  auth.Username,
+		Secret:    auth.Password,
+	}, nil
+}
+
+func (h *helper) list() (map[string]string, error) {
+	auths, err := h.auths()
+	if err != nil {
+		return nil, err
+	}
+
+	logins := make(map[string]string, len(auths))
+	for registry, auth := range auths {
+		logins[registry] = auth.Username
+	}
+	return logins, nil
+}
+
+type dockerAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// auths returns the Docker auths of a token for the role, keyed by registry
+// host.
+func (h *helper) auths() (map[string]dockerAuth, error) {
+	data, err := h.source.Read(tokenFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round trip through JSON to decode the auths into their structure
+	raw, err := json.Marshal(data["auths"])
+	if err != nil {
+		return nil, err
+	}
+	auths := make(map[string]dockerAuth)
+	if err := json.Unmarshal(raw, &auths); err != nil {
+		return nil, fmt.Errorf("unexpected docker auths in token response: %v", err)
+	}
+
+	normalized := make(map[string]dockerAuth, len(auths))
+	for registry, auth := range auths {
+		normalized[registryHost(registry)] = auth
+	}
+	return normalized, nil
+}
+
+// registryHost reduces a registry server URL, with or without a scheme, to
+// the host Docker uses to identify the registry.
+func registryHost(serverURL string) string {
+	if !strings.Contains(serverURL, "://") {
+		serverURL = "https://" + serverURL
+	}
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return serverURL
+	}
+	return u.Host
+}
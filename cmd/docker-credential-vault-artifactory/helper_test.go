@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credcache"
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credhelper"
+)
+
+func newTestHelper(t *testing.T, handler http.HandlerFunc) (*helper, func()) {
+	ts := httptest.NewServer(handler)
+
+	dir, err := ioutil.TempDir("", helperName)
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v\n", err)
+	}
+
+	config := api.DefaultConfig()
+	config.Address = ts.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create Vault client: %v\n", err)
+	}
+	client.SetToken("fake-vault-token")
+
+	h := &helper{
+		source: &credhelper.Source{
+			Vault: client,
+			Mount: "artifactory",
+			Role:  "ci",
+			Cache: credcache.New(dir),
+		},
+	}
+	return h, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestHelper(t *testing.T) {
+	requests := 0
+	h, cleanup := newTestHelper(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/artifactory/token/ci" {
+			t.Fatalf("Unexpected request path: %s\n", r.URL.Path)
+		}
+		if r.URL.Query().Get("format") != "docker" {
+			t.Fatalf("Expected docker format to be requested, got: %s\n", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_duration": 3600,
+			"data": map[string]interface{}{
+				"access_token": "abc123",
+				"auths": map[string]interface{}{
+					"docker.example.com": map[string]interface{}{
+						"username": "user",
+						"password": "abc123",
+					},
+				},
+			},
+		})
+	})
+	defer cleanup()
+
+	tests := []struct {
+		action      string
+		input       string
+		shouldError bool
+		output      string
+	}{
+		{"get", "https://docker.example.com", false, `{"ServerURL":"https://docker.example.com","Username":"user","Secret":"abc123"}`},
+		{"get", "docker.example.com", false, `{"ServerURL":"docker.example.com","Username":"user","Secret":"abc123"}`},
+		{"get", "https://other.example.com", true, errCredentialsNotFound.Error()},
+		{"get", "", true, ""},
+		{"list", "", false, `{"docker.example.com":"user"}`},
+		{"store", `{"ServerURL":"docker.example.com","Username":"user","Secret":"password"}`, false, ""},
+		{"unknown", "", true, ""},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		err := h.run(test.action, strings.NewReader(test.input), &out)
+		if test.shouldError {
+			if err == nil {
+				t.Fatalf("Expected %s %q to fail\n", test.action, test.input)
+			}
+			if test.output != "" && err.Error() != test.output {
+				t.Fatalf("Unexpected error for %s %q: %v\n", test.action, test.input, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Expected %s %q to succeed, got: %v\n", test.action, test.input, err)
+		}
+		if strings.TrimSpace(out.String()) != test.output {
+			t.Fatalf("Unexpected output for %s %q: %s\n", test.action, test.input, out.String())
+		}
+	}
+
+	if requests != 1 {
+		t.Fatalf("Expected the token to be read once and cached, got %d reads\n", requests)
+	}
+
+	if err := h.run("erase", strings.NewReader("docker.example.com"), ioutil.Discard); err != nil {
+		t.Fatalf("Failed to erase credentials: %v\n", err)
+	}
+	if err := h.run("get", strings.NewReader("docker.example.com"), ioutil.Discard); err != nil {
+		t.Fatalf("Failed to get credentials: %v\n", err)
+	}
+	if requests != 2 {
+		t.Fatalf("Expected erase to clear the cached token, got %d reads\n", requests)
+	}
+}
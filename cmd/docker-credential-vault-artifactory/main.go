@@ -0,0 +1,33 @@
+// docker-credential-vault-artifactory is a Docker credential helper which
+// authenticates to Artifactory Docker registries with access tokens issued by
+// the Vault Artifactory secrets engine.
+//
+// It is configured through the environment, see credhelper.NewSourceFromEnv.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credhelper"
+)
+
+const helperName = "docker-credential-vault-artifactory"
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s <get|store|erase|list>\n", helperName)
+		os.Exit(1)
+	}
+
+	source, err := credhelper.NewSourceFromEnv(helperName)
+	if err == nil {
+		h := &helper{source: source}
+		err = h.run(os.Args[1], os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		// Docker reads helper errors from stdout
+		fmt.Fprintln(os.Stdout, err)
+		os.Exit(1)
+	}
+}
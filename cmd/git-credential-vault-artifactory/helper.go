@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credhelper"
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+type helper struct {
+	source *credhelper.Source
+	// Artifactory address credentials are returned for, so that requests
+	// for other hosts are answered without contacting Vault
+	address string
+}
+
+func (h *helper) run(action string, args []string, in io.Reader, out io.Writer) error {
+	switch action {
+	case "get":
+		attrs, err := readAttributes(in)
+		if err != nil {
+			return err
+		}
+		// Tokens are only handed to the Artifactory host, so that a globally
+		// configured helper does not leak them to other remotes. Git moves on
+		// to the next helper when nothing is returned.
+		if ok, err := h.matchesArtifactory(attrs); err != nil || !ok {
+			return err
+		}
+		username, password, err := h.credentials()
+		if err != nil {
+			return err
+		}
+		// Echo the request back with the credentials filled in
+		attrs["username"] = username
+		attrs["password"] = password
+		return writeAttributes(out, attrs)
+	case "store":
+		// Credentials are issued by Vault, so they are not stored
+		_, err := ioutil.ReadAll(in)
+		return err
+	case "erase":
+		// Git erases credentials which were rejected by the server
+		attrs, err := readAttributes(in)
+		if err != nil {
+			return err
+		}
+		if ok, err := h.matchesArtifactory(attrs); err != nil || !ok {
+			return err
+		}
+		return h.source.Forget("")
+	case "netrc":
+		flags := flag.NewFlagSet(action, flag.ContinueOnError)
+		machine := flags.String("machine", "", "Host the .netrc entry applies to, defaults to the Artifactory host")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		username, password, err := h.credentials()
+		if err != nil {
+			return err
+		}
+		if *machine == "" {
+			*machine, err = h.artifactoryHost()
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(out, "machine %s login %s password %s\n", *machine, username, password)
+		return err
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// credentials returns the basic auth username and password for the role.
+func (h *helper) credentials() (string, string, error) {
+	data, err := h.source.Read("")
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, _ := data["access_token"].(string)
+	if accessToken == "" {
+		return "", "", errors.New("no access token in token response")
+	}
+
+	// Artifactory requires the basic auth username to match the token,
+	// older versions of the secrets engine do not return it.
+	username, _ := data["username"].(string)
+	if username == "" {
+		username = rtTokenService.TokenUsername(accessToken)
+	}
+	if username == "" {
+		return "", "", errors.New("unable to determine the username of the access token")
+	}
+
+	return username, accessToken, nil
+}
+
+// artifactoryURL returns the configured Artifactory address.
+func (h *helper) artifactoryURL() (*url.URL, error) {
+	if h.address == "" {
+		return nil, errors.New(addressEnv + " must be set")
+	}
+	u, err := url.Parse(h.address)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s %q", addressEnv, h.address)
+	}
+	return u, nil
+}
+
+// artifactoryHost returns the host of the configured Artifactory address,
+// or else of the address tokens are issued by.
+func (h *helper) artifactoryHost() (string, error) {
+	if h.address != "" {
+		u, err := h.artifactoryURL()
+		if err != nil {
+			return "", err
+		}
+		return u.Host, nil
+	}
+
+	data, err := h.source.Read("")
+	if err != nil {
+		return "", err
+	}
+	address, _ := data["address"].(string)
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return "", errors.New("unable to determine the Artifactory host, set -machine")
+	}
+	return u.Host, nil
+}
+
+// matchesArtifactory returns whether git is asking for credentials of the
+// configured Artifactory address, comparing the host and, when given, the
+// protocol.
+func (h *helper) matchesArtifactory(attrs map[string]string) (bool, error) {
+	if attrs["host"] == "" {
+		return false, nil
+	}
+	u, err := h.artifactoryURL()
+	if err != nil {
+		return false, err
+	}
+	if protocol := attrs["protocol"]; protocol != "" && !strings.EqualFold(protocol, u.Scheme) {
+		return false, nil
+	}
+	return strings.EqualFold(attrs["host"], u.Host), nil
+}
+
+// readAttributes reads the key=value lines git sends to credential helpers,
+// terminated by a blank line or the end of input.
+func readAttributes(in io.Reader) (map[string]string, error) {
+	attrs := make(map[string]string)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid credential attribute %q", line)
+		}
+		attrs[kv[0]] = kv[1]
+	}
+
+	return attrs, scanner.Err()
+}
+
+func writeAttributes(out io.Writer, attrs map[string]string) error {
+	// Only the attributes git acts upon are written back
+	for _, key := range []string{"protocol", "host", "path", "username", "password"} {
+		value, ok := attrs[key]
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Fprintf(out, "%s=%s\n", key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credcache"
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credhelper"
+)
+
+var fakeAccessToken = "eyJ0eXAiOiJKV1QifQ." +
+	base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"jfrt@01/users/vault-ci-1","jti":"1"}`)) +
+	".signature"
+
+func newTestHelper(t *testing.T, address string, handler http.HandlerFunc) (*helper, func()) {
+	ts := httptest.NewServer(handler)
+
+	dir, err := ioutil.TempDir("", helperName)
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v\n", err)
+	}
+
+	config := api.DefaultConfig()
+	config.Address = ts.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create Vault client: %v\n", err)
+	}
+	client.SetToken("fake-vault-token")
+
+	h := &helper{
+		source: &credhelper.Source{
+			Vault: client,
+			Mount: "artifactory",
+			Role:  "ci",
+			Cache: credcache.New(dir),
+		},
+		address: address,
+	}
+	return h, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestHelper(t *testing.T) {
+	requests := 0
+	h, cleanup := newTestHelper(t, "https://artifactory.example.com/artifactory/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/artifactory/token/ci" {
+			t.Fatalf("Unexpected request path: %s\n", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_duration": 3600,
+			"data": map[string]interface{}{
+				"access_token": fakeAccessToken,
+				"address":      "https://artifactory.example.com/artifactory/",
+			},
+		})
+	})
+	defer cleanup()
+
+	tests := []struct {
+		action      string
+		args        []string
+		input       string
+		shouldError bool
+		output      string
+	}{
+		{
+			"get",
+			nil,
+			"protocol=https\nhost=artifactory.example.com\n\n",
+			false,
+			"protocol=https\nhost=artifactory.example.com\nusername=vault-ci-1\npassword=" + fakeAccessToken + "\n",
+		},
+		{"get", nil, "invalid\n", true, ""},
+		// Tokens are not handed to other hosts
+		{"get", nil, "protocol=https\nhost=github.com\n\n", false, ""},
+		{"get", nil, "protocol=http\nhost=artifactory.example.com\n\n", false, ""},
+		{"get", nil, "protocol=https\nhost=artifactory.example.com:8443\n\n", false, ""},
+		{"get", nil, "protocol=https\n\n", false, ""},
+		{"erase", nil, "protocol=https\nhost=github.com\n\n", false, ""},
+		{"store", nil, "protocol=https\nhost=artifactory.example.com\nusername=user\npassword=secret\n", false, ""},
+		{
+			"netrc",
+			[]string{"-machine", "artifactory.example.com"},
+			"",
+			false,
+			"machine artifactory.example.com login vault-ci-1 password " + fakeAccessToken + "\n",
+		},
+		{
+			"netrc",
+			nil,
+			"",
+			false,
+			"machine artifactory.example.com login vault-ci-1 password " + fakeAccessToken + "\n",
+		},
+		{"unknown", nil, "", true, ""},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		err := h.run(test.action, test.args, strings.NewReader(test.input), &out)
+		if test.shouldError {
+			if err == nil {
+				t.Fatalf("Expected %s %v to fail\n", test.action, test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Expected %s %v to succeed, got: %v\n", test.action, test.args, err)
+		}
+		if out.String() != test.output {
+			t.Fatalf("Unexpected output for %s %v:\n%s\n", test.action, test.args, out.String())
+		}
+	}
+
+	if requests != 1 {
+		t.Fatalf("Expected the token to be read once and cached, got %d reads\n", requests)
+	}
+
+	if err := h.run("erase", nil, strings.NewReader("protocol=https\nhost=artifactory.example.com\n"), ioutil.Discard); err != nil {
+		t.Fatalf("Failed to erase credentials: %v\n", err)
+	}
+	if err := h.run("get", nil, strings.NewReader("host=artifactory.example.com\n"), ioutil.Discard); err != nil {
+		t.Fatalf("Failed to get credentials: %v\n", err)
+	}
+	if requests != 2 {
+		t.Fatalf("Expected erase to clear the cached token, got %d reads\n", requests)
+	}
+}
+
+func TestHelper_Address(t *testing.T) {
+	requests := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"lease_duration": 3600,
+			"data": map[string]interface{}{
+				"access_token": fakeAccessToken,
+				"address":      "https://artifactory.example.com/artifactory/",
+			},
+		})
+	}
+
+	// Other hosts are answered without contacting Vault
+	h, cleanup := newTestHelper(t, "https://artifactory.example.com/artifactory/", handler)
+	defer cleanup()
+
+	var out bytes.Buffer
+	if err := h.run("get", nil, strings.NewReader("protocol=https\nhost=github.com\n\n"), &out); err != nil {
+		t.Fatalf("Failed to get credentials: %v\n", err)
+	}
+	if out.Len() != 0 || requests != 0 {
+		t.Fatalf("Expected no output and no Vault requests for another host, got %q and %d requests\n", out.String(), requests)
+	}
+
+	// Without an address get fails rather than reading a token
+	h, cleanup = newTestHelper(t, "", handler)
+	defer cleanup()
+
+	if err := h.run("get", nil, strings.NewReader("protocol=https\nhost=github.com\n\n"), ioutil.Discard); err == nil {
+		t.Fatalf("Expected get without %s to fail\n", addressEnv)
+	}
+	if requests != 0 {
+		t.Fatalf("Expected no Vault requests without an address, got %d\n", requests)
+	}
+
+	// netrc reads a token anyway and defaults to the address it was issued by
+	out.Reset()
+	if err := h.run("netrc", nil, strings.NewReader(""), &out); err != nil {
+		t.Fatalf("Failed to write netrc: %v\n", err)
+	}
+	if out.String() != "machine artifactory.example.com login vault-ci-1 password "+fakeAccessToken+"\n" {
+		t.Fatalf("Unexpected netrc output:\n%s\n", out.String())
+	}
+}
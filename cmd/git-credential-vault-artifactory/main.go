@@ -0,0 +1,39 @@
+// git-credential-vault-artifactory is a git credential helper which provides
+// HTTP basic auth credentials for Artifactory using access tokens issued by
+// the Vault Artifactory secrets engine. It can also write .netrc entries for
+// tools such as the go command which read credentials from .netrc.
+//
+// It is configured through the environment, see credhelper.NewSourceFromEnv.
+// VAULT_ARTIFACTORY_ADDRESS is the Artifactory address credentials are
+// returned for, git's requests for other hosts are answered without
+// contacting Vault.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credhelper"
+)
+
+const helperName = "git-credential-vault-artifactory"
+
+const addressEnv = "VAULT_ARTIFACTORY_ADDRESS"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s <get|store|erase>\n", helperName)
+		fmt.Fprintf(os.Stderr, "       %s netrc [-machine <host>]\n", helperName)
+		os.Exit(1)
+	}
+
+	source, err := credhelper.NewSourceFromEnv(helperName)
+	if err == nil {
+		h := &helper{source: source, address: os.Getenv(addressEnv)}
+		err = h.run(os.Args[1], os.Args[2:], os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
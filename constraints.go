@@ -0,0 +1,129 @@
+package artifactory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+)
+
+// permitted reports whether a value matches one of the allowed globs, or
+// none are set, and none of the denied globs.
+func permitted(value string, allowed, denied []string) bool {
+	value = strings.ToLower(value)
+	matches := func(globs []string) bool {
+		for _, glob := range globs {
+			if strutil.GlobbedStringsMatch(strings.ToLower(glob), value) {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(allowed) == 0 || matches(allowed)) && !matches(denied)
+}
+
+// checkRole returns an error describing the first way a role breaks the
+// constraints. Groups and users created for each lease are managed by the
+// engine and not constrained, but the repositories and actions they are
+// granted are.
+func (c *constraintsConfig) checkRole(role *roleConfig) error {
+	if role.Username != "" && !permitted(role.Username, c.AllowedUsernames, c.DeniedUsernames) {
+		return fmt.Errorf("username %s is not permitted", role.Username)
+	}
+	for _, group := range role.MemberOfGroups {
+		if !permitted(group, c.AllowedGroups, c.DeniedGroups) {
+			return fmt.Errorf("group %s is not permitted", group)
+		}
+	}
+	for _, repository := range role.PermissionRepositories {
+		if !permitted(repository, c.AllowedRepositories, c.DeniedRepositories) {
+			return fmt.Errorf("repository %s is not permitted", repository)
+		}
+	}
+	if err := c.checkActions(role.PermissionActions); err != nil {
+		return err
+	}
+	if role.GroupTemplate != "" {
+		tmpl, err := parseGroupTemplate(role.GroupTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid group_template: %v", err)
+		}
+		for _, name := range tmpl.targetNames() {
+			if !permitted(name, c.AllowedPermissionTargets, c.DeniedPermissionTargets) {
+				return fmt.Errorf("permission target %s is not permitted", name)
+			}
+			if err := c.checkActions(tmpl.PermissionTargets[name]); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.checkTTL(role.TTL); err != nil {
+		return err
+	}
+	return c.checkScope(constrainedScope(role))
+}
+
+// checkActions checks the actions granted by a permission target.
+func (c *constraintsConfig) checkActions(actions []string) error {
+	for _, action := range actions {
+		if !permitted(action, c.AllowedActions, c.DeniedActions) {
+			return fmt.Errorf("action %s is not permitted", action)
+		}
+	}
+	return nil
+}
+
+// constrainedScope is the scope of a role's tokens without the group created
+// for each lease, so that a role is checked the same way when it is written
+// and when tokens are issued. A role whose only group is created by the
+// engine has no scope to check.
+func constrainedScope(role *roleConfig) string {
+	if len(role.MemberOfGroups) == 0 && role.GroupTemplate != "" {
+		return ""
+	}
+	return (&leaseResources{}).scope(role)
+}
+
+// checkScope checks each scope of a token request.
+func (c *constraintsConfig) checkScope(scope string) error {
+	for _, s := range strings.Fields(scope) {
+		for _, single := range splitGroupsScope(s) {
+			if !permitted(single, c.AllowedScopes, c.DeniedScopes) {
+				return fmt.Errorf("scope %s is not permitted", single)
+			}
+		}
+	}
+	return nil
+}
+
+// splitGroupsScope splits a member-of-groups scope into one scope per group,
+// so that a glob matching one group does not permit the others listed with
+// it. Other scopes are returned as they are.
+func splitGroupsScope(scope string) []string {
+	const prefix = "member-of-groups:"
+	if !strings.HasPrefix(scope, prefix) {
+		return []string{scope}
+	}
+
+	var scopes []string
+	for _, group := range strings.Split(strings.TrimPrefix(scope, prefix), ",") {
+		scopes = append(scopes, prefix+group)
+	}
+	return scopes
+}
+
+// checkTTL rejects TTLs over the maximum. A role without a TTL issues tokens
+// which never expire, so is rejected whenever there is a maximum.
+func (c *constraintsConfig) checkTTL(ttl time.Duration) error {
+	if c.MaxTTL <= 0 {
+		return nil
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be set, the maximum is %s", c.MaxTTL)
+	}
+	if ttl > c.MaxTTL {
+		return fmt.Errorf("ttl %s exceeds the maximum of %s", ttl, c.MaxTTL)
+	}
+	return nil
+}
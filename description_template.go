@@ -0,0 +1,97 @@
+package artifactory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// Artifactory rejects token descriptions longer than this
+const maxDescriptionSize = 1024
+
+// descriptionTemplateData is the data available to a role's description
+// template. The description is rendered before the token is created, so
+// nothing about the token itself is available.
+type descriptionTemplateData struct {
+	RoleName        string
+	RoleDescription string
+	Tags            []string
+	Metadata        map[string]string
+	Username        string
+	ProjectKey      string
+	DisplayName     string
+	EntityID        string
+}
+
+func parseDescriptionTemplate(text string) (*template.Template, error) {
+	return template.New("description_template").Funcs(outputTemplateFuncs).Parse(text)
+}
+
+// validateDescriptionTemplate parses the template and renders it with
+// placeholder data, catching references to unknown fields before a token is
+// created.
+func validateDescriptionTemplate(text string) error {
+	_, err := renderDescriptionTemplate(text, &descriptionTemplateData{
+		RoleName:        "role",
+		RoleDescription: "description",
+		Tags:            []string{"tag"},
+		Metadata:        map[string]string{"key": "value"},
+		Username:        "username",
+		ProjectKey:      "project",
+		DisplayName:     "token-display-name",
+		EntityID:        "entity-id",
+	})
+	return err
+}
+
+// defaultDescription describes the Access API tokens of a role without a
+// description template by the role's description, tags and metadata, e.g.
+// "CI builds; tags: ci, maven; metadata: team=platform". It is truncated to
+// the size Artifactory accepts rather than failing the request.
+func defaultDescription(role *roleConfig) string {
+	var parts []string
+	if role.Description != "" {
+		parts = append(parts, role.Description)
+	}
+	if len(role.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(role.Tags, ", "))
+	}
+	if len(role.Metadata) > 0 {
+		keys := make([]string, 0, len(role.Metadata))
+		for k := range role.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = fmt.Sprintf("%s=%s", k, role.Metadata[k])
+		}
+		parts = append(parts, "metadata: "+strings.Join(pairs, ", "))
+	}
+
+	description := strings.Join(parts, "; ")
+	if len(description) > maxDescriptionSize {
+		n := maxDescriptionSize
+		for n > 0 && !utf8.RuneStart(description[n]) {
+			n--
+		}
+		description = description[:n]
+	}
+	return description
+}
+
+func renderDescriptionTemplate(text string, data *descriptionTemplateData) (string, error) {
+	tmpl, err := parseDescriptionTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	out := &limitedBuffer{limit: maxDescriptionSize}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,148 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtGroupService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/group"
+	rtPermissionService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/permission"
+)
+
+// groupTemplate describes the group created for each lease of a role.
+type groupTemplate struct {
+	Description string `json:"description"`
+	// Existing permission targets whose repositories the group is granted,
+	// with the actions granted on each
+	PermissionTargets map[string][]string `json:"permission_targets"`
+}
+
+// targetNames returns the names of the template's permission targets,
+// sorted.
+func (t *groupTemplate) targetNames() []string {
+	names := make([]string, 0, len(t.PermissionTargets))
+	for name := range t.PermissionTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// leasePermissionTargets returns the names of the permission targets created
+// for the group of a lease, one for each permission target of the template.
+func (t *groupTemplate) leasePermissionTargets(group string) []string {
+	names := make([]string, len(t.PermissionTargets))
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d", group, i+1)
+	}
+	return names
+}
+
+func parseGroupTemplate(raw string) (*groupTemplate, error) {
+	tmpl := &groupTemplate{}
+	if err := json.Unmarshal([]byte(raw), tmpl); err != nil {
+		return nil, err
+	}
+
+	if len(tmpl.PermissionTargets) == 0 {
+		return nil, errors.New("permission_targets cannot be empty")
+	}
+	for name, actions := range tmpl.PermissionTargets {
+		if len(actions) == 0 {
+			return nil, fmt.Errorf("permission target %q has no actions", name)
+		}
+		for _, action := range actions {
+			if _, ok := permissionActions[action]; !ok {
+				return nil, fmt.Errorf("permission target %q has unsupported action %q", name, action)
+			}
+		}
+	}
+
+	return tmpl, nil
+}
+
+// createDynamicGroup creates a group for a single lease from the role's
+// group template. The group is granted the repositories of each of the
+// template's permission targets through a permission target of its own, so
+// that the existing permission targets, which may be changed concurrently by
+// administrators or other Vault nodes, are never modified.
+func (b *backend) createDynamicGroup(ctx context.Context, s logical.Storage, r *leaseResources, role *roleConfig) error {
+	tmpl, err := parseGroupTemplate(role.GroupTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid group_template: %v", err)
+	}
+	sources := tmpl.targetNames()
+	if len(sources) != len(r.GroupPermissionTargets) {
+		return fmt.Errorf("group_template has %d permission targets, expected %d", len(sources), len(r.GroupPermissionTargets))
+	}
+	name := r.DynamicGroup
+
+	groupService, err := b.groupService(ctx, s)
+	if err != nil {
+		return err
+	}
+	permissionService, err := b.permissionService(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	description := tmpl.Description
+	if description == "" {
+		description = "Created by Vault for a single lease"
+	}
+	err = groupService.CreateGroup(&rtGroupService.CreateGroupRequest{
+		Name:        name,
+		Description: description,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to create group: %v", err)
+	}
+	b.Logger().Info("created dynamic group", "name", name)
+
+	for i, source := range sources {
+		target, err := permissionService.GetPermissionTarget(source)
+		if err != nil {
+			return fmt.Errorf("Failed to read permission target %s: %v", source, err)
+		}
+		if target == nil {
+			return fmt.Errorf("permission target %s does not exist", source)
+		}
+
+		actions := make([]string, 0, len(tmpl.PermissionTargets[source]))
+		for _, action := range tmpl.PermissionTargets[source] {
+			actions = append(actions, permissionActions[action])
+		}
+		err = permissionService.CreatePermissionTarget(&rtPermissionService.CreatePermissionTargetRequest{
+			Name:            r.GroupPermissionTargets[i],
+			Repositories:    target.Repositories,
+			IncludePatterns: target.IncludePatterns,
+			ExcludePatterns: target.ExcludePatterns,
+			Groups:          map[string][]string{name: actions},
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to create permission target for %s: %v", source, err)
+		}
+		b.Logger().Info("created permission target", "name", r.GroupPermissionTargets[i], "source", source, "group", name)
+	}
+
+	return nil
+}
+
+// deleteDynamicGroup deletes a group created for a lease.
+func (b *backend) deleteDynamicGroup(ctx context.Context, s logical.Storage, name string) error {
+	groupService, err := b.groupService(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if err := groupService.DeleteGroup(name); err != nil {
+		return fmt.Errorf("Failed to delete group %s: %v", name, err)
+	}
+	b.Logger().Info("deleted dynamic group", "name", name)
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+func TestDynamicGroup_Lifecycle(t *testing.T) {
+	var requests []string
+	var tokenScope string
+	var leaseTarget struct {
+		Repo struct {
+			Repositories []string `json:"repositories"`
+			Actions      struct {
+				Groups map[string][]string `json:"groups"`
+			} `json:"actions"`
+		} `json:"repo"`
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/security/token":
+			tokenScope = r.FormValue("scope")
+			body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+				AccessToken: "abc123",
+				ExpiresIn:   3600,
+				Scope:       "api:* member-of-groups:vault-test-1",
+				TokenType:   "Bearer",
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		case r.URL.Path == "/api/security/token/revoke":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/api/v2/security/permissions/libs" && r.Method == http.MethodGet:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			w.Write([]byte(`{"name":"libs","repo":{"repositories":["libs-release-local"],"actions":{}}}`))
+		case r.URL.Path == "/api/v2/security/permissions/vault-test-1-1" && r.Method == http.MethodPut:
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if err := json.NewDecoder(r.Body).Decode(&leaseTarget); err != nil {
+				t.Fatalf("Unable to decode permission target: %v\n", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(r.URL.Path, "/api/v2/security/permissions/"),
+			strings.HasPrefix(r.URL.Path, "/api/security/groups/"):
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Fatalf("Unexpected request path: %s\n", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// The dynamic group is left out of the scope checked on write and issue
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/constraints",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"allowed_scopes":             "member-of-groups:team-*",
+			"allowed_permission_targets": "libs",
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"group_template": `{"permission_targets": {"libs": ["read"]}}`,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+		ID:        "1",
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if tokenScope != "member-of-groups:vault-test-1" {
+		t.Fatalf("Expected token to be scoped to the dynamic group, got: %s\n", tokenScope)
+	}
+
+	// The group is granted the repositories of the existing permission
+	// target through a permission target of its own
+	if len(leaseTarget.Repo.Repositories) != 1 || leaseTarget.Repo.Repositories[0] != "libs-release-local" {
+		t.Fatalf("Expected lease permission target to copy repositories, got: %#v\n", leaseTarget)
+	}
+	if actions := leaseTarget.Repo.Actions.Groups["vault-test-1"]; len(actions) != 1 || actions[0] != "read" {
+		t.Fatalf("Expected lease permission target to grant the group, got: %#v\n", leaseTarget)
+	}
+
+	// InternalData is read back from storage as JSON
+	resp.Secret.InternalData["group_permission_targets"] = []interface{}{"vault-test-1-1"}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+		Data:      resp.Data,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	expected := []string{
+		"PUT /api/security/groups/vault-test-1",
+		"GET /api/v2/security/permissions/libs",
+		"PUT /api/v2/security/permissions/vault-test-1-1",
+		"DELETE /api/v2/security/permissions/vault-test-1-1",
+		"DELETE /api/security/groups/vault-test-1",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected requests %v, got: %v\n", expected, requests)
+	}
+}
@@ -0,0 +1,65 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtUserService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/user"
+)
+
+// Artifactory requires users to have an email address, dynamic users are
+// given one under a reserved domain so it can never be delivered.
+const dynamicUserEmailDomain = "vault.invalid"
+
+// createDynamicUser creates an Artifactory user for a single lease. The user
+// cannot log in with its password, only with the tokens issued to it.
+func (b *backend) createDynamicUser(ctx context.Context, s logical.Storage, username string, groups []string) error {
+	userService, err := b.userService(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	password, err := generateUserPassword()
+	if err != nil {
+		return err
+	}
+
+	err = userService.CreateUser(&rtUserService.CreateUserRequest{
+		Name:     username,
+		Email:    fmt.Sprintf("%s@%s", username, dynamicUserEmailDomain),
+		Password: password,
+		Groups:   groups,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to create user: %v", err)
+	}
+	b.Logger().Info("created dynamic user", "username", username)
+
+	return nil
+}
+
+func (b *backend) deleteDynamicUser(ctx context.Context, s logical.Storage, username string) error {
+	userService, err := b.userService(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if err := userService.DeleteUser(username); err != nil {
+		return fmt.Errorf("Failed to delete user %s: %v", username, err)
+	}
+	b.Logger().Info("deleted dynamic user", "username", username)
+
+	return nil
+}
+
+func generateUserPassword() (string, error) {
+	buf, err := uuid.GenerateRandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,116 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+func TestDynamicUser_Lifecycle(t *testing.T) {
+	var created, deleted []string
+	var createdGroups []string
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/security/token":
+			body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+				AccessToken: "abc123",
+				ExpiresIn:   3600,
+				Scope:       "api:* member-of-groups:readers",
+				TokenType:   "Bearer",
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		case r.URL.Path == "/api/security/token/revoke":
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/api/security/users/"):
+			username := strings.TrimPrefix(r.URL.Path, "/api/security/users/")
+			switch r.Method {
+			case http.MethodPut:
+				var user struct {
+					Groups []string `json:"groups"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+					t.Fatalf("Unable to decode user from request: %v\n", err)
+				}
+				created = append(created, username)
+				createdGroups = user.Groups
+				w.WriteHeader(http.StatusCreated)
+			case http.MethodDelete:
+				deleted = append(deleted, username)
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Fatalf("Unexpected request method: %s\n", r.Method)
+			}
+		default:
+			t.Fatalf("Unexpected request path: %s\n", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"member_of_groups": "readers",
+			"skip_validation":  true,
+			"user_mode":        "dynamic",
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+		ID:        "1",
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if len(created) != 1 || created[0] != "vault-test-1" {
+		t.Fatalf("Expected user vault-test-1 to be created, got: %v\n", created)
+	}
+	if len(createdGroups) != 1 || createdGroups[0] != "readers" {
+		t.Fatalf("Expected user to be created with the role groups, got: %v\n", createdGroups)
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("Expected no users to be deleted before revoke, got: %v\n", deleted)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+		Data:      resp.Data,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if len(deleted) != 1 || deleted[0] != "vault-test-1" {
+		t.Fatalf("Expected user vault-test-1 to be deleted on revoke, got: %v\n", deleted)
+	}
+}
@@ -0,0 +1,85 @@
+package artifactory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Output formats which token reads can render the access token into, in
+// addition to the raw token.
+const (
+	formatDocker = "docker"
+)
+
+// tokenFormatData holds everything needed to render an access token into an
+// output format.
+type tokenFormatData struct {
+	Role        *roleConfig
+	Username    string
+	AccessToken string
+	Address     string
+}
+
+// tokenFormatter renders an access token into the response fields of an
+// output format.
+type tokenFormatter func(*tokenFormatData) (map[string]interface{}, error)
+
+var tokenFormatters = map[string]tokenFormatter{
+	formatDocker: renderDocker,
+	formatNpm:    renderNpm,
+	formatPypi:   renderPypi,
+	formatMaven:  renderMaven,
+	formatGradle: renderGradle,
+	formatHelm:   renderHelm,
+	formatNuget:  renderNuget,
+	formatGo:     renderGo,
+}
+
+// validateTokenFormat checks that tokens of the role can be rendered in the
+// format, so that a token is not created only to fail rendering.
+func validateTokenFormat(format string, role *roleConfig) error {
+	if format == "" {
+		return nil
+	}
+	if _, ok := tokenFormatters[format]; !ok {
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	if isPackageFormat(format) && role.Repositories[format] == "" {
+		return fmt.Errorf("role has no repository configured for the %s format", format)
+	}
+	return nil
+}
+
+// renderDocker produces the auths section of a Docker config.json, and the
+// complete config.json encoded as a Kubernetes .dockerconfigjson secret value.
+func renderDocker(data *tokenFormatData) (map[string]interface{}, error) {
+	registry := data.Role.DockerRegistry
+	if registry == "" {
+		// Docker repositories are typically served from the Artifactory host
+		u, err := url.Parse(data.Address)
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine docker registry from address: %v", err)
+		}
+		registry = u.Host
+	}
+
+	auths := map[string]interface{}{
+		registry: map[string]interface{}{
+			"username": data.Username,
+			"password": data.AccessToken,
+			"auth":     base64.StdEncoding.EncodeToString([]byte(data.Username + ":" + data.AccessToken)),
+		},
+	}
+
+	config, err := json.Marshal(map[string]interface{}{"auths": auths})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"auths":            auths,
+		"dockerconfigjson": base64.StdEncoding.EncodeToString(config),
+	}, nil
+}
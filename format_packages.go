@@ -0,0 +1,251 @@
+package artifactory
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Package manager formats render the credential file a client needs to use
+// the repository configured for the format on the role.
+const (
+	formatNpm    = "npm"
+	formatPypi   = "pypi"
+	formatMaven  = "maven"
+	formatGradle = "gradle"
+	formatHelm   = "helm"
+	formatNuget  = "nuget"
+	formatGo     = "go"
+)
+
+// isPackageFormat returns whether format is a package manager format, which
+// requires a repository configured on the role.
+func isPackageFormat(format string) bool {
+	_, ok := tokenFormatters[format]
+	return ok && format != formatDocker
+}
+
+// repository returns the repository key configured on the role for a format.
+func (d *tokenFormatData) repository(format string) (string, error) {
+	repo := d.Role.Repositories[format]
+	if repo == "" {
+		return "", fmt.Errorf("role has no repository configured for the %s format", format)
+	}
+	return repo, nil
+}
+
+// repositoryURL builds the URL of a repository served by the given
+// Artifactory package API, e.g. api/npm/<repo>.
+func (d *tokenFormatData) repositoryURL(api, repo string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(d.Address, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Artifactory address: %v", err)
+	}
+	if api != "" {
+		u.Path += "/api/" + api
+	}
+	u.Path += "/" + repo
+	return u, nil
+}
+
+// repositoryURLWithAuth is repositoryURL with the credentials embedded, for
+// clients which only accept credentials as part of the URL.
+func (d *tokenFormatData) repositoryURLWithAuth(api, repo string) (*url.URL, error) {
+	u, err := d.repositoryURL(api, repo)
+	if err != nil {
+		return nil, err
+	}
+	u.User = url.UserPassword(d.Username, d.AccessToken)
+	return u, nil
+}
+
+// renderNpm produces an .npmrc using the repository as the default registry.
+func renderNpm(data *tokenFormatData) (map[string]interface{}, error) {
+	repo, err := data.repository(formatNpm)
+	if err != nil {
+		return nil, err
+	}
+	u, err := data.repositoryURL("npm", repo)
+	if err != nil {
+		return nil, err
+	}
+	registry := u.String() + "/"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "registry=%s\n", registry)
+	fmt.Fprintf(&b, "%s:_authToken=%s\n", strings.TrimPrefix(registry, u.Scheme+":"), data.AccessToken)
+	fmt.Fprintf(&b, "always-auth=true\n")
+
+	return map[string]interface{}{"npmrc": b.String()}, nil
+}
+
+// renderPypi produces a pip.conf using the repository as the index.
+func renderPypi(data *tokenFormatData) (map[string]interface{}, error) {
+	repo, err := data.repository(formatPypi)
+	if err != nil {
+		return nil, err
+	}
+	u, err := data.repositoryURLWithAuth("pypi", repo)
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/simple"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[global]\n")
+	fmt.Fprintf(&b, "index-url = %s\n", u.String())
+
+	return map[string]interface{}{"pip_conf": b.String()}, nil
+}
+
+type mavenSettings struct {
+	XMLName xml.Name      `xml:"settings"`
+	Servers []mavenServer `xml:"servers>server"`
+	Mirrors []mavenMirror `xml:"mirrors>mirror"`
+}
+
+type mavenServer struct {
+	ID       string `xml:"id"`
+	Username string `xml:"username"`
+	Password string `xml:"password"`
+}
+
+type mavenMirror struct {
+	ID       string `xml:"id"`
+	Name     string `xml:"name"`
+	URL      string `xml:"url"`
+	MirrorOf string `xml:"mirrorOf"`
+}
+
+// renderMaven produces a settings.xml mirroring all repositories through
+// the configured repository.
+func renderMaven(data *tokenFormatData) (map[string]interface{}, error) {
+	repo, err := data.repository(formatMaven)
+	if err != nil {
+		return nil, err
+	}
+	u, err := data.repositoryURL("", repo)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := xml.MarshalIndent(&mavenSettings{
+		Servers: []mavenServer{{ID: repo, Username: data.Username, Password: data.AccessToken}},
+		Mirrors: []mavenMirror{{ID: repo, Name: repo, URL: u.String(), MirrorOf: "*"}},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"settings_xml": xml.Header + string(settings) + "\n"}, nil
+}
+
+// renderGradle produces a gradle.properties with the properties used by the
+// Gradle Artifactory plugin.
+func renderGradle(data *tokenFormatData) (map[string]interface{}, error) {
+	repo, err := data.repository(formatGradle)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "artifactory_contextUrl=%s\n", escapeProperty(strings.TrimSuffix(data.Address, "/")))
+	fmt.Fprintf(&b, "artifactory_repoKey=%s\n", escapeProperty(repo))
+	fmt.Fprintf(&b, "artifactory_user=%s\n", escapeProperty(data.Username))
+	fmt.Fprintf(&b, "artifactory_password=%s\n", escapeProperty(data.AccessToken))
+
+	return map[string]interface{}{"gradle_properties": b.String()}, nil
+}
+
+// escapeProperty escapes a value for a Java .properties file.
+func escapeProperty(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ":", `\:`, "=", `\=`, "\n", `\n`).Replace(value)
+}
+
+// renderHelm produces a Helm repositories.yaml containing the repository.
+func renderHelm(data *tokenFormatData) (map[string]interface{}, error) {
+	repo, err := data.repository(formatHelm)
+	if err != nil {
+		return nil, err
+	}
+	u, err := data.repositoryURL("helm", repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "apiVersion: v1\n")
+	fmt.Fprintf(&b, "repositories:\n")
+	fmt.Fprintf(&b, "- name: %s\n", yamlString(repo))
+	fmt.Fprintf(&b, "  url: %s\n", yamlString(u.String()))
+	fmt.Fprintf(&b, "  username: %s\n", yamlString(data.Username))
+	fmt.Fprintf(&b, "  password: %s\n", yamlString(data.AccessToken))
+
+	return map[string]interface{}{"helm_repositories_yaml": b.String()}, nil
+}
+
+// yamlString quotes a value as a YAML double quoted scalar, which accepts
+// the same escapes as a JSON string.
+func yamlString(value string) string {
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+// Key of the package source in NuGet.Config. Credentials are stored in an
+// element named after the source, so a fixed key is used rather than the
+// repository key, which need not be a valid XML name.
+const nugetSourceKey = "Artifactory"
+
+type nugetConfig struct {
+	XMLName xml.Name        `xml:"configuration"`
+	Sources []nugetKeyValue `xml:"packageSources>add"`
+	// The element name must match nugetSourceKey
+	Credentials []nugetKeyValue `xml:"packageSourceCredentials>Artifactory>add"`
+}
+
+type nugetKeyValue struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// renderNuget produces a NuGet.Config with the repository as a package
+// source.
+func renderNuget(data *tokenFormatData) (map[string]interface{}, error) {
+	repo, err := data.repository(formatNuget)
+	if err != nil {
+		return nil, err
+	}
+	u, err := data.repositoryURL("nuget", repo)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := xml.MarshalIndent(&nugetConfig{
+		Sources: []nugetKeyValue{{Key: nugetSourceKey, Value: u.String()}},
+		Credentials: []nugetKeyValue{
+			{Key: "Username", Value: data.Username},
+			{Key: "ClearTextPassword", Value: data.AccessToken},
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"nuget_config": xml.Header + string(config) + "\n"}, nil
+}
+
+// renderGo produces a GOPROXY value pointing at the repository.
+func renderGo(data *tokenFormatData) (map[string]interface{}, error) {
+	repo, err := data.repository(formatGo)
+	if err != nil {
+		return nil, err
+	}
+	u, err := data.repositoryURLWithAuth("go", repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"goproxy": u.String()}, nil
+}
@@ -5,9 +5,13 @@ go 1.12
 require (
 	github.com/google/pprof v0.0.0-20190515194954-54271f7e092f // indirect
 	github.com/hashicorp/go-hclog v0.8.0
+	github.com/hashicorp/go-multierror v1.0.0
+	github.com/hashicorp/go-uuid v1.0.1
 	github.com/hashicorp/vault/api v1.0.4
 	github.com/hashicorp/vault/sdk v0.1.13
 	github.com/ianlancetaylor/demangle v0.0.0-20181102032728-5e5cf60278f6 // indirect
 	github.com/jfrog/jfrog-client-go v0.5.0
+	github.com/mitchellh/mapstructure v1.1.2
 	golang.org/x/arch v0.0.0-20190312162104-788fe5ffcd8c // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,78 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtGroupService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/group"
+)
+
+// knownGroups holds the groups of Artifactory, listed at most once so that
+// many roles can be validated with a single request.
+type knownGroups struct {
+	// Lower cased group names, nil until listed
+	names map[string]bool
+}
+
+// validateRoleGroups checks that every group a role's tokens are scoped to
+// exists, so that mistakes surface when the role is written rather than when
+// a token is requested. Roles cannot be validated before the engine is
+// configured. The groups are listed into known unless it already holds them,
+// known may be nil to validate a single role.
+func (b *backend) validateRoleGroups(ctx context.Context, s logical.Storage, role *roleConfig, known *knownGroups) (*logical.Response, error) {
+	var groups []string
+	for _, group := range role.MemberOfGroups {
+		if group != "*" {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	conf, err := b.readConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return nil, nil
+	}
+
+	if known == nil {
+		known = &knownGroups{}
+	}
+	if known.names == nil {
+		groupService, err := b.groupService(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		existing, err := groupService.GetGroups()
+		switch {
+		case err == rtGroupService.ErrGroupsForbidden:
+			return logical.ErrorResponse("configured credentials cannot list groups, set skip_validation to write the role without validating member_of_groups"), nil
+		case err != nil:
+			return nil, fmt.Errorf("Failed to list groups: %v", err)
+		}
+
+		// Group names are case insensitive
+		known.names = make(map[string]bool, len(existing))
+		for _, group := range existing {
+			known.names[strings.ToLower(group)] = true
+		}
+	}
+
+	var unknown []string
+	for _, group := range groups {
+		if !known.names[strings.ToLower(group)] {
+			unknown = append(unknown, group)
+		}
+	}
+	if len(unknown) > 0 {
+		return logical.ErrorResponse(fmt.Sprintf("member_of_groups contains groups which do not exist: %s", strings.Join(unknown, ", "))), nil
+	}
+
+	return nil, nil
+}
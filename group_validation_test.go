@@ -0,0 +1,148 @@
+package artifactory
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestRole_ValidateGroups(t *testing.T) {
+	groupsHandler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/security/groups/" {
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`[{"name": "readers"}, {"name": "Writers"}]`))
+	}
+	forbiddenHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}
+
+	tests := []struct {
+		expectation Expectation
+		role        map[string]interface{}
+		handler     http.HandlerFunc
+	}{
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"member_of_groups": "readers,writers"},
+			groupsHandler,
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"member_of_groups": "readers,deployers"},
+			groupsHandler,
+		},
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"member_of_groups": "readers,deployers", "skip_validation": true},
+			groupsHandler,
+		},
+		{
+			ExpectedToSucceed, // Tokens scoped to all groups of the user
+			map[string]interface{}{"username": "user", "allow_all_groups": true},
+			forbiddenHandler,
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"member_of_groups": "readers"},
+			forbiddenHandler,
+		},
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"member_of_groups": "readers", "skip_validation": true},
+			forbiddenHandler,
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		b, storage := newBackend(t)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"address":    ts.URL + "/",
+				"api_key":    "abc123",
+				"tls_verify": false,
+			},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test",
+			Storage:   storage,
+			Data:      test.role,
+		})
+		assertLogicalResponse(t, test.expectation, err, resp)
+		if test.expectation == FailWithLogicalError && resp == nil {
+			t.Fatalf("Expected role %v to fail validation\n", test.role)
+		}
+	}
+}
+
+func TestRole_ValidateGroupsOnChange(t *testing.T) {
+	groups := `[{"name": "readers"}, {"name": "writers"}]`
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/security/groups/" {
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(groups))
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"member_of_groups": "readers,writers"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// writers is deleted from Artifactory after the role was written
+	groups = `[{"name": "readers"}]`
+
+	tests := []struct {
+		expectation Expectation
+		role        map[string]interface{}
+	}{
+		{ExpectedToSucceed, map[string]interface{}{"max_ttl": "2h"}},
+		{ExpectedToSucceed, map[string]interface{}{"member_of_groups": "writers,readers"}},
+		{FailWithLogicalError, map[string]interface{}{"member_of_groups": "readers,deployers"}},
+		{ExpectedToSucceed, map[string]interface{}{"member_of_groups": "readers"}},
+	}
+
+	for _, test := range tests {
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/test",
+			Storage:   storage,
+			Data:      test.role,
+		})
+		assertLogicalResponse(t, test.expectation, err, resp)
+		if test.expectation == FailWithLogicalError && resp == nil {
+			t.Fatalf("Expected role %v to fail validation\n", test.role)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+// periodicFunc is invoked by Vault's rollback manager roughly every minute,
+// each housekeeping task is only run once its configured interval elapses.
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// Storage is read-only on standbys and performance secondaries share
+	// their storage with the primary, which is responsible for housekeeping.
+	replicationState := b.System().ReplicationState()
+	if replicationState.HasState(consts.ReplicationPerformanceStandby) ||
+		(replicationState.HasState(consts.ReplicationPerformanceSecondary) && !b.System().LocalMount()) {
+		return nil
+	}
+
+	conf, err := b.readHousekeepingConfig(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	b.housekeepingLock.Lock()
+	defer b.housekeepingLock.Unlock()
+
+	now := time.Now()
+	var merr *multierror.Error
+
+	if housekeepingDue(b.lastTidy, conf.TidyInterval, now) {
+		if err := b.tidyTokens(ctx, req.Storage, now); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("error tidying access tokens: %v", err))
+		} else {
+			b.lastTidy = now
+		}
+	}
+
+	if housekeepingDue(b.lastIssuanceCleanup, conf.IssuanceCleanupInterval, now) {
+		// Records of transient users are left for the tidy task, which can
+		// still act on them.
+		if err := b.cleanupIssuanceRecords(ctx, req.Storage, now, conf.TidyInterval > 0); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("error cleaning up issuance records: %v", err))
+		} else {
+			b.lastIssuanceCleanup = now
+		}
+	}
+
+	if housekeepingDue(b.lastCredentialCheck, conf.CredentialCheckInterval, now) {
+		if err := b.checkCredentialExpiry(ctx, req.Storage, now, conf.CredentialExpiryWarning); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("error checking credential expiry: %v", err))
+		} else {
+			b.lastCredentialCheck = now
+		}
+	}
+
+	if housekeepingDue(b.lastAccessTokenRotation, conf.AccessTokenRotation, now) {
+		if err := b.rotateAccessToken(ctx, req.Storage); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("error rotating access token: %v", err))
+		} else {
+			b.lastAccessTokenRotation = now
+		}
+	}
+
+	if housekeepingDue(b.lastRoleHistoryCleanup, conf.RoleHistoryCleanup, now) {
+		if err := b.cleanupDeletedRoleHistory(ctx, req.Storage, now.Add(-conf.DeletedRoleRetention)); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("error cleaning up role history: %v", err))
+		} else {
+			b.lastRoleHistoryCleanup = now
+		}
+	}
+
+	return merr.ErrorOrNil()
+}
+
+func housekeepingDue(lastRun time.Time, interval time.Duration, now time.Time) bool {
+	return interval > 0 && now.Sub(lastRun) >= interval
+}
+
+// tidyTokens revokes the tokens of transient users whose lease has ended
+// without being revoked, deleting any user, permission target or group
+// created for the lease.
+// Transient usernames are unique to a single lease, so any token still
+// issued to them is orphaned.
+func (b *backend) tidyTokens(ctx context.Context, s logical.Storage, now time.Time) error {
+	ids, err := s.List(ctx, issuancePrefix)
+	if err != nil {
+		return err
+	}
+
+	orphaned := make(map[string]*issuanceRecord)
+	orphanedIDs := make([]string, 0, len(ids))
+	var leaseRecords []*issuanceRecord
+	for _, id := range ids {
+		record, err := readIssuanceRecord(ctx, s, id)
+		if err != nil {
+			return err
+		}
+		if record == nil || !record.Transient || record.ExpiresAt.After(now) {
+			continue
+		}
+		orphaned[record.Username] = record
+		orphanedIDs = append(orphanedIDs, id)
+		if record.DynamicUser || record.PermissionTarget != "" || record.DynamicGroup != "" {
+			leaseRecords = append(leaseRecords, record)
+		}
+	}
+	if len(orphaned) == 0 {
+		return nil
+	}
+
+	tokenService, err := b.tokenService(ctx, s)
+	if err != nil {
+		return err
+	}
+	tokens, err := tokenService.GetTokens()
+	if err != nil {
+		return fmt.Errorf("Failed to list access tokens: %v", err)
+	}
+
+	for _, token := range tokens.Tokens {
+		record, ok := orphaned[token.Username()]
+		if !ok {
+			continue
+		}
+		err := tokenService.RevokeToken(&rtTokenService.RevokeTokenRequest{TokenID: token.TokenID, AccessApi: record.AccessApi || record.ProjectKey != ""})
+		if err != nil {
+			return fmt.Errorf("Failed to revoke token %s: %v", token.TokenID, err)
+		}
+		b.Logger().Info("revoked orphaned access token", "username", token.Username(), "token_id", token.TokenID)
+	}
+
+	for _, record := range leaseRecords {
+		if err := b.deleteLeaseResources(ctx, s, record.leaseResources()); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range orphanedIDs {
+		if err := deleteIssuanceRecord(ctx, s, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupIssuanceRecords removes records for leases which have ended.
+func (b *backend) cleanupIssuanceRecords(ctx context.Context, s logical.Storage, now time.Time, skipTransient bool) error {
+	ids, err := s.List(ctx, issuancePrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		record, err := readIssuanceRecord(ctx, s, id)
+		if err != nil {
+			return err
+		}
+		if record == nil || record.ExpiresAt.After(now) {
+			continue
+		}
+		if record.Transient && skipTransient {
+			continue
+		}
+		if err := deleteIssuanceRecord(ctx, s, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupDeletedRoleHistory removes the history of roles deleted before
+// deletedBefore, after which they can no longer be restored. The history of
+// existing roles is limited by the number of versions kept instead.
+func (b *backend) cleanupDeletedRoleHistory(ctx context.Context, s logical.Storage, deletedBefore time.Time) error {
+	names, err := listNamespacedKeys(ctx, s, "role-history/")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := b.cleanupRoleHistory(ctx, s, name, deletedBefore); err != nil {
+			return fmt.Errorf("Failed to clean up history of role %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (b *backend) cleanupRoleHistory(ctx context.Context, s logical.Storage, name string, deletedBefore time.Time) error {
+	// The role may be recreated concurrently
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	history, err := readRoleHistory(ctx, s, name)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return nil
+	}
+	last := history[len(history)-1]
+	if last.Role != nil || !last.Time.Before(deletedBefore) {
+		return nil
+	}
+
+	if err := writeRoleHistory(ctx, s, name, nil); err != nil {
+		return err
+	}
+	b.Logger().Info("removed history of deleted role", "role", name, "deleted_at", last.Time)
+	return nil
+}
+
+// rotateAccessToken replaces the configured access token with a new token for
+// the same user, with the same scope and lifetime, and revokes the old one.
+// API keys, passwords and reference tokens cannot be reissued, so are not
+// rotated.
+func (b *backend) rotateAccessToken(ctx context.Context, s logical.Storage) error {
+	conf, err := b.readConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	if conf == nil || conf.AccessToken == "" {
+		return nil
+	}
+
+	claims, err := rtTokenService.ParseClaims(conf.AccessToken)
+	switch {
+	case err == rtTokenService.ErrNotJWT:
+		return nil
+	case err != nil:
+		return fmt.Errorf("Failed to decode access_token: %v", err)
+	}
+	username := claims.Username()
+	if username == "" {
+		return fmt.Errorf("access_token subject %q is not a user", claims.Subject)
+	}
+	var expiresIn int64
+	if claims.ExpiresAt > 0 {
+		expiresIn = claims.ExpiresAt - claims.IssuedAt
+	}
+
+	tokenService, err := b.tokenService(ctx, s)
+	if err != nil {
+		return err
+	}
+	token, err := tokenService.CreateToken(&rtTokenService.CreateTokenRequest{
+		Username:  username,
+		Scope:     claims.Scope,
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to create access token: %v", err)
+	}
+
+	oldToken := conf.AccessToken
+	conf.AccessToken = token.AccessToken
+	entry, err := logical.StorageEntryJSON("config", conf)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return err
+	}
+	b.resetClient()
+	b.Logger().Info("rotated configured access token", "username", username, "token_id", token.TokenID)
+
+	// The old token expires on its own if it cannot be revoked, failing
+	// here would only rotate the new token again
+	tokenService, err = b.tokenService(ctx, s)
+	if err == nil {
+		err = tokenService.RevokeToken(&rtTokenService.RevokeTokenRequest{Token: oldToken})
+	}
+	if err != nil {
+		b.Logger().Warn("failed to revoke previous access token", "token_id", claims.ID, "error", err)
+	}
+	return nil
+}
+
+// checkCredentialExpiry logs a warning once the configured access token is
+// due to expire within warning, and an error once it has expired, so that it
+// can be replaced before tokens can no longer be issued. API keys and
+// passwords do not expire.
+func (b *backend) checkCredentialExpiry(ctx context.Context, s logical.Storage, now time.Time, warning time.Duration) error {
+	conf, err := b.readConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	if conf == nil {
+		return nil
+	}
+
+	expiresAt, err := conf.accessTokenExpiry()
+	if err != nil {
+		return err
+	}
+	if expiresAt.IsZero() {
+		return nil
+	}
+
+	switch {
+	case !now.Before(expiresAt):
+		b.Logger().Error("configured access token has expired", "expires_at", expiresAt)
+	case expiresAt.Sub(now) <= warning:
+		b.Logger().Warn("configured access token expires soon", "expires_at", expiresAt)
+	}
+	return nil
+}
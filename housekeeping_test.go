@@ -0,0 +1,357 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+func TestHousekeeping_Periodic(t *testing.T) {
+	var revoked []string
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/security/token":
+			body, err := json.Marshal(&rtTokenService.GetTokensResponse{
+				Tokens: []rtTokenService.TokenInfo{
+					{TokenID: "token-expired", Subject: "jfrt@01/users/vault-test-expired"},
+					{TokenID: "token-active", Subject: "jfrt@01/users/vault-test-active"},
+					{TokenID: "token-user", Subject: "jfrt@01/users/user"},
+				},
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		case "/api/security/token/revoke":
+			revoked = append(revoked, r.FormValue("token_id"))
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Fatalf("Unexpected request path: %s\n", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	records := map[string]*issuanceRecord{
+		"expired": {RoleName: "test", Username: "vault-test-expired", Transient: true, ExpiresAt: time.Now().Add(-time.Hour)},
+		"active":  {RoleName: "test", Username: "vault-test-active", Transient: true, ExpiresAt: time.Now().Add(time.Hour)},
+		"user":    {RoleName: "test", Username: "user", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	for id, record := range records {
+		if err := putIssuanceRecord(ctx, storage, id, record); err != nil {
+			t.Fatalf("Failed to write issuance record: %v\n", err)
+		}
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if len(revoked) != 1 || revoked[0] != "token-expired" {
+		t.Fatalf("Expected only token-expired to be revoked, got: %v\n", revoked)
+	}
+
+	ids, err := storage.List(ctx, issuancePrefix)
+	if err != nil {
+		t.Fatalf("Failed to list issuance records: %v\n", err)
+	}
+	if len(ids) != 1 || ids[0] != "active" {
+		t.Fatalf("Expected only the active issuance record to remain, got: %v\n", ids)
+	}
+
+	// Tasks should not run again until their interval has elapsed
+	if err := putIssuanceRecord(ctx, storage, "expired", records["expired"]); err != nil {
+		t.Fatalf("Failed to write issuance record: %v\n", err)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if len(revoked) != 1 {
+		t.Fatalf("Expected tidy not to run again before its interval, got: %v\n", revoked)
+	}
+}
+
+func TestHousekeeping_IssuanceRecordLifecycle(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/security/token":
+			body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+				AccessToken: "abc123",
+				ExpiresIn:   3600,
+				TokenType:   "Bearer",
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"member_of_groups": "group", "skip_validation": true},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	issuanceID := resp.Secret.InternalData["issuance_id"].(string)
+	record, err := readIssuanceRecord(ctx, storage, issuanceID)
+	if err != nil || record == nil {
+		t.Fatalf("Expected issuance record to be written, got: %v %v\n", record, err)
+	}
+	if !record.Transient {
+		t.Fatalf("Expected issuance record for transient user, got: %v\n", record)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+		Data:      resp.Data,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	record, err = readIssuanceRecord(ctx, storage, issuanceID)
+	if err != nil || record != nil {
+		t.Fatalf("Expected issuance record to be removed on revoke, got: %v %v\n", record, err)
+	}
+}
+
+func TestHousekeeping_CredentialExpiry(t *testing.T) {
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(48 * time.Hour).Truncate(time.Second).UTC()
+	accessToken := "header." + base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"jfrt@01/users/admin","exp":%d}`, expiresAt.Unix()))) + ".signature"
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":      "https://127.0.0.1/",
+			"access_token": accessToken,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if resp.Data["access_token_expires_at"] != expiresAt.Format(time.RFC3339) {
+		t.Fatalf("Expected access token expiry %s, got: %v\n", expiresAt.Format(time.RFC3339), resp.Data)
+	}
+
+	tests := []struct {
+		name        string
+		accessToken string
+		expiresAt   time.Time
+	}{
+		{"jwt", accessToken, expiresAt},
+		{"reference token", "cmVmdGtuOjAx", time.Time{}},
+		{"no expiry", "header." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"jfrt@01/users/admin"}`)) + ".signature", time.Time{}},
+		{"none", "", time.Time{}},
+	}
+	for _, test := range tests {
+		conf := &accessConfig{AccessToken: test.accessToken}
+		got, err := conf.accessTokenExpiry()
+		if err != nil {
+			t.Fatalf("Failed to read expiry of %s: %v\n", test.name, err)
+		}
+		if !got.Equal(test.expiresAt) {
+			t.Fatalf("Expected %s to expire at %v, got: %v\n", test.name, test.expiresAt, got)
+		}
+	}
+	if _, err := (&accessConfig{AccessToken: "a.!.c"}).accessTokenExpiry(); err == nil {
+		t.Fatalf("Expected undecodable access token to fail\n")
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if b.(*backend).lastCredentialCheck.IsZero() {
+		t.Fatalf("Expected credential expiry to be checked\n")
+	}
+}
+
+func TestHousekeeping_DeletedRoleHistory(t *testing.T) {
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"teamA/deleted", "kept"} {
+		resp, err := writeRoleAs(t, b, storage, "alice", logical.CreateOperation, "roles/"+name, map[string]interface{}{
+			"member_of_groups": "readers",
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+	resp, err := writeRoleAs(t, b, storage, "alice", logical.DeleteOperation, "roles/teamA/deleted", nil)
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// The deleted role can be restored until the retention elapses
+	if err := b.(*backend).cleanupDeletedRoleHistory(ctx, storage, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to clean up role history: %v\n", err)
+	}
+	if history, _ := readRoleHistory(ctx, storage, "teamA/deleted"); len(history) != 2 {
+		t.Fatalf("Expected history of recently deleted role to be kept, got: %#v\n", history)
+	}
+
+	if err := b.(*backend).cleanupDeletedRoleHistory(ctx, storage, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to clean up role history: %v\n", err)
+	}
+	if history, _ := readRoleHistory(ctx, storage, "teamA/deleted"); history != nil {
+		t.Fatalf("Expected history of deleted role to be removed, got: %#v\n", history)
+	}
+	if history, _ := readRoleHistory(ctx, storage, "kept"); len(history) != 1 {
+		t.Fatalf("Expected history of existing role to be kept, got: %#v\n", history)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RollbackOperation,
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if b.(*backend).lastRoleHistoryCleanup.IsZero() {
+		t.Fatalf("Expected role history to be cleaned up\n")
+	}
+}
+
+func TestHousekeeping_AccessTokenRotation(t *testing.T) {
+	jwt := func(id string, issuedAt int64) string {
+		claims := fmt.Sprintf(`{"jti":%q,"sub":"jfrt@01/users/vault","scp":"member-of-groups:admins api:*","iat":%d,"exp":%d}`, id, issuedAt, issuedAt+3600)
+		return "header." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+	}
+	now := time.Now().Unix()
+	oldToken, newToken := jwt("old", now-60), jwt("new", now)
+
+	var created, revoked bool
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Unable to parse request form: %v\n", err)
+		}
+		switch r.URL.Path {
+		case "/api/security/token":
+			if r.Header.Get("Authorization") != "Bearer "+oldToken {
+				t.Fatalf("Expected new token to be created with the configured token\n")
+			}
+			if r.Form.Get("username") != "vault" || r.Form.Get("scope") != "member-of-groups:admins api:*" || r.Form.Get("expires_in") != "3600" {
+				t.Fatalf("Expected token for the same user, scope and lifetime, got: %v\n", r.Form)
+			}
+			created = true
+			w.Write([]byte(fmt.Sprintf(`{"access_token": %q, "expires_in": 3600, "token_type": "Bearer"}`, newToken)))
+		case "/api/security/token/revoke":
+			if r.Header.Get("Authorization") != "Bearer "+newToken {
+				t.Fatalf("Expected old token to be revoked with the new token\n")
+			}
+			if r.Form.Get("token") != oldToken {
+				t.Fatalf("Expected old token to be revoked, got: %v\n", r.Form)
+			}
+			revoked = true
+		default:
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":      ts.URL + "/",
+			"access_token": oldToken,
+			"tls_verify":   false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if err := b.(*backend).rotateAccessToken(ctx, storage); err != nil {
+		t.Fatalf("Failed to rotate access token: %v\n", err)
+	}
+	if !created || !revoked {
+		t.Fatalf("Expected a new token to be created and the old one revoked\n")
+	}
+	conf, err := b.(*backend).readConfig(ctx, storage)
+	if err != nil || conf.AccessToken != newToken {
+		t.Fatalf("Expected the new token to be configured, got: %#v err:%v\n", conf, err)
+	}
+
+	// API keys cannot be reissued
+	created, revoked = false, false
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if err := b.(*backend).rotateAccessToken(ctx, storage); err != nil || created {
+		t.Fatalf("Expected api_key not to be rotated, err:%v\n", err)
+	}
+}
@@ -0,0 +1,68 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const issuancePrefix = "issuance/"
+
+// issuanceRecord tracks an access token handed out under a lease. It is
+// removed when the lease is revoked, so a record which outlives its lease
+// points at a token which may still be valid in Artifactory.
+type issuanceRecord struct {
+	RoleName         string    `json:"role_name"`
+	Username         string    `json:"username"`
+	Transient        bool      `json:"transient"`
+	DynamicUser      bool      `json:"dynamic_user"`
+	PermissionTarget string    `json:"permission_target"`
+	DynamicGroup     string    `json:"dynamic_group"`
+	ProjectKey       string    `json:"project_key"`
+	AccessApi        bool      `json:"access_api"`
+	ExpiresAt        time.Time `json:"expires_at"`
+
+	GroupPermissionTargets []string `json:"group_permission_targets"`
+}
+
+func readIssuanceRecord(ctx context.Context, s logical.Storage, id string) (*issuanceRecord, error) {
+	raw, err := s.Get(ctx, issuancePrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	record := new(issuanceRecord)
+	if err := raw.DecodeJSON(record); err != nil {
+		return nil, fmt.Errorf("error reading issuance record %s: %v", id, err)
+	}
+
+	return record, nil
+}
+
+func putIssuanceRecord(ctx context.Context, s logical.Storage, id string, record *issuanceRecord) error {
+	entry, err := logical.StorageEntryJSON(issuancePrefix+id, record)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func deleteIssuanceRecord(ctx context.Context, s logical.Storage, id string) error {
+	return s.Delete(ctx, issuancePrefix+id)
+}
+
+func (r *issuanceRecord) leaseResources() *leaseResources {
+	return &leaseResources{
+		Username:         r.Username,
+		DynamicUser:      r.DynamicUser,
+		PermissionTarget: r.PermissionTarget,
+		DynamicGroup:     r.DynamicGroup,
+
+		GroupPermissionTargets: r.GroupPermissionTargets,
+	}
+}
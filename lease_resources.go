@@ -0,0 +1,85 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// leaseResources are the Artifactory entities created for a single lease,
+// which must be removed when the lease ends.
+type leaseResources struct {
+	Username         string
+	DynamicUser      bool
+	PermissionTarget string
+	DynamicGroup     string
+	// Permission targets granting the dynamic group its repositories
+	GroupPermissionTargets []string
+}
+
+// createLeaseResources creates the entities required by the role, the
+// dynamic group first so that the user can be made a member of it.
+func (b *backend) createLeaseResources(ctx context.Context, s logical.Storage, r *leaseResources, role *roleConfig) error {
+	if r.DynamicGroup != "" {
+		if err := b.createDynamicGroup(ctx, s, r, role); err != nil {
+			return err
+		}
+	}
+	if r.DynamicUser {
+		if err := b.createDynamicUser(ctx, s, r.Username, r.groups(role)); err != nil {
+			return err
+		}
+	}
+	if r.PermissionTarget != "" {
+		if err := b.createPermissionTarget(ctx, s, r.PermissionTarget, r.Username, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteLeaseResources removes the entities created for a lease. The
+// permission target and group are removed first so that they never outlive
+// the user they grant access to.
+func (b *backend) deleteLeaseResources(ctx context.Context, s logical.Storage, r *leaseResources) error {
+	if r.PermissionTarget != "" {
+		if err := b.deletePermissionTarget(ctx, s, r.PermissionTarget); err != nil {
+			return err
+		}
+	}
+	for _, target := range r.GroupPermissionTargets {
+		if err := b.deletePermissionTarget(ctx, s, target); err != nil {
+			return err
+		}
+	}
+	if r.DynamicGroup != "" {
+		if err := b.deleteDynamicGroup(ctx, s, r.DynamicGroup); err != nil {
+			return err
+		}
+	}
+	if r.DynamicUser {
+		return b.deleteDynamicUser(ctx, s, r.Username)
+	}
+	return nil
+}
+
+// groups returns the groups the lease's user is a member of.
+func (r *leaseResources) groups(role *roleConfig) []string {
+	groups := append([]string{}, role.MemberOfGroups...)
+	if r.DynamicGroup != "" {
+		groups = append(groups, r.DynamicGroup)
+	}
+	return groups
+}
+
+// scope is the scope of the lease's token. Roles relying on a permission
+// target alone scope the token to the user's own permissions.
+func (r *leaseResources) scope(role *roleConfig) string {
+	groups := r.groups(role)
+	if len(groups) == 0 {
+		groups = []string{"*"}
+	}
+	return fmt.Sprintf("member-of-groups:%s", strings.Join(groups, ","))
+}
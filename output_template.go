@@ -0,0 +1,95 @@
+package artifactory
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Rendered templates are returned in the token response, so their size is
+// bounded to keep a bad template from producing an enormous response.
+const maxOutputTemplateSize = 64 * 1024
+
+// outputTemplateData is the data available to a role's output template.
+type outputTemplateData struct {
+	RoleName    string
+	Username    string
+	AccessToken string
+	Scope       string
+	ExpiresIn   int64
+	ExpiresAt   time.Time
+	Address     string
+}
+
+// outputTemplateFuncs is the complete set of functions available to output
+// templates in addition to text/template's builtins. None of them can reach
+// outside the template data.
+var outputTemplateFuncs = template.FuncMap{
+	"base64": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    strings.Replace,
+	"trimPrefix": strings.TrimPrefix,
+	"trimSuffix": strings.TrimSuffix,
+	"rfc3339": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+}
+
+func parseOutputTemplate(text string) (*template.Template, error) {
+	return template.New("output_template").Funcs(outputTemplateFuncs).Parse(text)
+}
+
+// validateOutputTemplate parses the template and renders it with placeholder
+// data, catching references to unknown fields before a token is created.
+func validateOutputTemplate(text string) error {
+	_, err := renderOutputTemplate(text, &outputTemplateData{
+		RoleName:    "role",
+		Username:    "username",
+		AccessToken: "access-token",
+		Scope:       "member-of-groups:group",
+		ExpiresIn:   3600,
+		ExpiresAt:   time.Now().Add(time.Hour),
+		Address:     "https://artifactory.example.com/artifactory/",
+	})
+	return err
+}
+
+func renderOutputTemplate(text string, data *outputTemplateData) (string, error) {
+	tmpl, err := parseOutputTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	out := &limitedBuffer{limit: maxOutputTemplateSize}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+var errOutputTemplateTooLarge = errors.New("rendered output template exceeds the maximum size")
+
+// limitedBuffer is a bytes.Buffer which refuses writes past its limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errOutputTemplateTooLarge
+	}
+	return b.Buffer.Write(p)
+}
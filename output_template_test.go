@@ -0,0 +1,55 @@
+package artifactory
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		valid    bool
+		template string
+	}{
+		{true, `{{ .Username }}:{{ .AccessToken }}`},
+		{true, `{{ printf "%s:%s" .Username .AccessToken | base64 }}`},
+		{true, `{"token": {{ json .AccessToken }}, "expires": "{{ rfc3339 .ExpiresAt }}"}`},
+		{true, `{{ trimSuffix .Address "/" }}/api/npm/{{ .RoleName | lower }}/`},
+		{false, `{{ .Username `},
+		{false, `{{ .Password }}`},
+		{false, `{{ env "HOME" }}`},
+	}
+
+	for _, test := range tests {
+		err := validateOutputTemplate(test.template)
+		if test.valid && err != nil {
+			t.Fatalf("Expected template %q to be valid, got error: %v\n", test.template, err)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("Expected template %q to be invalid\n", test.template)
+		}
+	}
+}
+
+func TestOutputTemplate_Render(t *testing.T) {
+	data := &outputTemplateData{
+		RoleName:    "test",
+		Username:    "user",
+		AccessToken: "abc123",
+		ExpiresAt:   time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC),
+		Address:     "https://example.com/artifactory/",
+	}
+
+	rendered, err := renderOutputTemplate(`{{ .Username }} {{ .AccessToken | base64 }} {{ rfc3339 .ExpiresAt }}`, data)
+	if err != nil {
+		t.Fatalf("Failed to render template: %v\n", err)
+	}
+	if rendered != "user YWJjMTIz 2019-06-01T12:00:00Z" {
+		t.Fatalf("Unexpected rendered output: %s\n", rendered)
+	}
+
+	huge := strings.Repeat("{{ .AccessToken }}", maxOutputTemplateSize/len(data.AccessToken)+1)
+	if _, err := renderOutputTemplate(huge, data); err == nil {
+		t.Fatal("Expected rendering output larger than the limit to fail")
+	}
+}
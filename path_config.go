@@ -3,9 +3,12 @@ package artifactory
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
 )
 
 func pathConfig(b *backend) *framework.Path {
@@ -28,6 +31,14 @@ func pathConfig(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Password of the user which will be used to create access tokens",
 			},
+			"access_token": {
+				Type:        framework.TypeString,
+				Description: "Access token to use to create access tokens, such as a project admin token",
+			},
+			"project_key": {
+				Type:        framework.TypeString,
+				Description: "Key of the JFrog project the credentials administer. All roles issue tokens for this project.",
+			},
 			"tls_verify": {
 				Type:        framework.TypeBool,
 				Description: "Disable TLS verification of Artifactory server",
@@ -55,6 +66,7 @@ func (b *backend) readConfig(ctx context.Context, storage logical.Storage) (*acc
 	if err := entry.DecodeJSON(conf); err != nil {
 		return nil, fmt.Errorf("error reading artifactory configuration: %v", err)
 	}
+	upgradeConfig(conf)
 
 	return conf, nil
 }
@@ -68,20 +80,34 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, data
 		return nil, fmt.Errorf("No artifactory configuration found")
 	}
 
+	var accessTokenExpiresAt string
+	expiresAt, err := conf.accessTokenExpiry()
+	if err != nil {
+		return nil, err
+	}
+	if !expiresAt.IsZero() {
+		accessTokenExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"address": conf.Address,
+			"address":                 conf.Address,
+			"project_key":             conf.ProjectKey,
+			"access_token_expires_at": accessTokenExpiresAt,
 		},
 	}, nil
 }
 
 func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	config := accessConfig{
-		Address:   data.Get("address").(string),
-		ApiKey:    data.Get("api_key").(string),
-		Username:  data.Get("username").(string),
-		Password:  data.Get("password").(string),
-		TlsVerify: data.Get("tls_verify").(bool),
+		Version:     configStorageVersion,
+		Address:     data.Get("address").(string),
+		ApiKey:      data.Get("api_key").(string),
+		Username:    data.Get("username").(string),
+		Password:    data.Get("password").(string),
+		AccessToken: data.Get("access_token").(string),
+		ProjectKey:  data.Get("project_key").(string),
+		TlsVerify:   data.Get("tls_verify").(bool),
 	}
 	if config.Address == "" {
 		return logical.ErrorResponse("address must be set"), nil
@@ -89,13 +115,24 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 	if config.ApiKey != "" && config.Username != "" {
 		return logical.ErrorResponse("provide either api_key or username, not both"), nil
 	}
+	if config.AccessToken != "" && (config.ApiKey != "" || config.Username != "") {
+		return logical.ErrorResponse("provide either access_token, api_key or username, not more than one"), nil
+	}
 
 	if config.Username != "" {
 		if config.Password == "" {
 			return logical.ErrorResponse("must provide password with username"), nil
 		}
-	} else if config.ApiKey == "" {
-		return logical.ErrorResponse("api_key must be set"), nil
+	} else if config.ApiKey == "" && config.AccessToken == "" {
+		return logical.ErrorResponse("access_token, api_key or username must be set"), nil
+	}
+	// The expiry is read from the token, so one which cannot be decoded
+	// would break reading the config and the credential check
+	if _, err := config.accessTokenExpiry(); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("invalid access_token: %v", err)), nil
+	}
+	if config.ProjectKey != "" && !projectKeyRegex.MatchString(config.ProjectKey) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid project_key %q", config.ProjectKey)), nil
 	}
 
 	entry, err := logical.StorageEntryJSON("config", config)
@@ -106,17 +143,43 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 		return nil, err
 	}
 
+	b.resetClient()
+
 	return nil, nil
 }
 
 type accessConfig struct {
-	Address   string `json:"address"`
-	ApiKey    string `json:"api_key"`
-	Username  string `json:"username"`
-	Password  string `json:"password"`
-	TlsVerify bool   `json:"tls_verify"`
+	Version     int    `json:"version"`
+	Address     string `json:"address"`
+	ApiKey      string `json:"api_key"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	AccessToken string `json:"access_token"`
+	ProjectKey  string `json:"project_key"`
+	TlsVerify   bool   `json:"tls_verify"`
+}
+
+// accessTokenExpiry returns when the configured access token expires, or the
+// zero time if there is no access token or it does not expire. Reference
+// tokens cannot be decoded, so are treated as not expiring.
+func (c *accessConfig) accessTokenExpiry() (time.Time, error) {
+	if c.AccessToken == "" {
+		return time.Time{}, nil
+	}
+
+	claims, err := rtTokenService.ParseClaims(c.AccessToken)
+	switch {
+	case err == rtTokenService.ErrNotJWT:
+		return time.Time{}, nil
+	case err != nil:
+		return time.Time{}, fmt.Errorf("Failed to decode access_token: %v", err)
+	}
+	if claims.ExpiresAt <= 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(claims.ExpiresAt, 0).UTC(), nil
 }
 
 const pathConfigRootHelpSyn = `
-Configure the address and API key to access the Artifactory server.
+Configure the address and credentials to access the Artifactory server.
 `
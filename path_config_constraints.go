@@ -0,0 +1,215 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathConfigConstraints(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/constraints",
+		Fields: map[string]*framework.FieldSchema{
+			"allowed_usernames": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the usernames roles may issue tokens for. Any username is allowed if empty.",
+			},
+			"denied_usernames": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the usernames roles may not issue tokens for.",
+			},
+			"allowed_groups": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the groups roles may scope tokens to. Any group is allowed if empty.",
+			},
+			"denied_groups": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the groups roles may not scope tokens to.",
+			},
+			"allowed_scopes": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the scopes tokens may be issued with, e.g. member-of-groups:team-*. Any scope is allowed if empty.",
+			},
+			"denied_scopes": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the scopes tokens may not be issued with.",
+			},
+			"allowed_permission_targets": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the permission targets group templates may grant. Any permission target is allowed if empty.",
+			},
+			"denied_permission_targets": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the permission targets group templates may not grant.",
+			},
+			"allowed_repositories": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the permission_repositories of roles. Any repository is allowed if empty.",
+			},
+			"denied_repositories": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Globs of the permission_repositories roles may not set.",
+			},
+			"allowed_actions": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The actions permission targets created for leases may grant. Any action is allowed if empty.",
+			},
+			"denied_actions": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "The actions permission targets created for leases may not grant.",
+			},
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum TTL of roles. Unlimited if 0.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigConstraintsRead,
+			logical.UpdateOperation: b.pathConfigConstraintsWrite,
+		},
+		HelpSynopsis:    pathConfigConstraintsHelpSyn,
+		HelpDescription: pathConfigConstraintsHelpDesc,
+	}
+}
+
+func (b *backend) readConstraintsConfig(ctx context.Context, storage logical.Storage) (*constraintsConfig, error) {
+	entry, err := storage.Get(ctx, "config/constraints")
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &constraintsConfig{}
+	if entry == nil {
+		return conf, nil
+	}
+
+	if err := entry.DecodeJSON(conf); err != nil {
+		return nil, fmt.Errorf("error reading constraints configuration: %v", err)
+	}
+
+	return conf, nil
+}
+
+func (b *backend) pathConfigConstraintsRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readConstraintsConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"allowed_usernames":          conf.AllowedUsernames,
+			"denied_usernames":           conf.DeniedUsernames,
+			"allowed_groups":             conf.AllowedGroups,
+			"denied_groups":              conf.DeniedGroups,
+			"allowed_scopes":             conf.AllowedScopes,
+			"denied_scopes":              conf.DeniedScopes,
+			"allowed_permission_targets": conf.AllowedPermissionTargets,
+			"denied_permission_targets":  conf.DeniedPermissionTargets,
+			"allowed_repositories":       conf.AllowedRepositories,
+			"denied_repositories":        conf.DeniedRepositories,
+			"allowed_actions":            conf.AllowedActions,
+			"denied_actions":             conf.DeniedActions,
+			"max_ttl":                    int64(conf.MaxTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigConstraintsWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readConstraintsConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if allowed, ok := data.GetOk("allowed_usernames"); ok {
+		conf.AllowedUsernames = allowed.([]string)
+	}
+	if denied, ok := data.GetOk("denied_usernames"); ok {
+		conf.DeniedUsernames = denied.([]string)
+	}
+	if allowed, ok := data.GetOk("allowed_groups"); ok {
+		conf.AllowedGroups = allowed.([]string)
+	}
+	if denied, ok := data.GetOk("denied_groups"); ok {
+		conf.DeniedGroups = denied.([]string)
+	}
+	if allowed, ok := data.GetOk("allowed_scopes"); ok {
+		conf.AllowedScopes = allowed.([]string)
+	}
+	if denied, ok := data.GetOk("denied_scopes"); ok {
+		conf.DeniedScopes = denied.([]string)
+	}
+	if allowed, ok := data.GetOk("allowed_permission_targets"); ok {
+		conf.AllowedPermissionTargets = allowed.([]string)
+	}
+	if denied, ok := data.GetOk("denied_permission_targets"); ok {
+		conf.DeniedPermissionTargets = denied.([]string)
+	}
+	if allowed, ok := data.GetOk("allowed_repositories"); ok {
+		conf.AllowedRepositories = allowed.([]string)
+	}
+	if denied, ok := data.GetOk("denied_repositories"); ok {
+		conf.DeniedRepositories = denied.([]string)
+	}
+	if allowed, ok := data.GetOk("allowed_actions"); ok {
+		conf.AllowedActions = allowed.([]string)
+	}
+	if denied, ok := data.GetOk("denied_actions"); ok {
+		conf.DeniedActions = denied.([]string)
+	}
+	if maxTTL, ok := data.GetOk("max_ttl"); ok {
+		conf.MaxTTL = time.Duration(maxTTL.(int)) * time.Second
+	}
+	if conf.MaxTTL < 0 {
+		return logical.ErrorResponse("max_ttl cannot be negative"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config/constraints", conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// constraintsConfig limits what roles can grant, so that writing roles can be
+// delegated. It is enforced when roles are written and tokens are issued.
+type constraintsConfig struct {
+	AllowedUsernames         []string      `json:"allowed_usernames"`
+	DeniedUsernames          []string      `json:"denied_usernames"`
+	AllowedGroups            []string      `json:"allowed_groups"`
+	DeniedGroups             []string      `json:"denied_groups"`
+	AllowedScopes            []string      `json:"allowed_scopes"`
+	DeniedScopes             []string      `json:"denied_scopes"`
+	AllowedPermissionTargets []string      `json:"allowed_permission_targets"`
+	DeniedPermissionTargets  []string      `json:"denied_permission_targets"`
+	AllowedRepositories      []string      `json:"allowed_repositories"`
+	DeniedRepositories       []string      `json:"denied_repositories"`
+	AllowedActions           []string      `json:"allowed_actions"`
+	DeniedActions            []string      `json:"denied_actions"`
+	MaxTTL                   time.Duration `json:"max_ttl"`
+}
+
+const pathConfigConstraintsHelpSyn = `
+Configure the usernames, groups, scopes, permissions and TTL roles are limited to.
+`
+
+const pathConfigConstraintsHelpDesc = `
+Each allowed and denied list holds globs with a leading or trailing *. A value
+is permitted if it matches an allowed glob, or no allowed globs are set, and
+matches no denied glob. Matching is case insensitive. A lone * only matches
+the * group, which scopes tokens to all groups of the user.
+
+Permission targets, repositories and actions constrain the group_template,
+permission_repositories and permission_actions of roles. Groups created for
+each lease are left out of the checked scope.
+
+Constraints are checked when roles are written and again when tokens are
+issued, so tightening them also applies to existing roles.
+`
@@ -0,0 +1,226 @@
+package artifactory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestConstraints_Permitted(t *testing.T) {
+	tests := []struct {
+		permitted bool
+		value     string
+		allowed   []string
+		denied    []string
+	}{
+		{true, "readers", nil, nil},
+		{true, "team-a-readers", []string{"team-a-*"}, nil},
+		{true, "Team-A-Readers", []string{"team-a-*"}, nil},
+		{false, "team-b-readers", []string{"team-a-*"}, nil},
+		{false, "team-a-admins", []string{"team-a-*"}, []string{"*-admins"}},
+		{false, "*", nil, []string{"*"}},
+		{true, "readers", nil, []string{"*"}},
+	}
+
+	for _, test := range tests {
+		if permitted(test.value, test.allowed, test.denied) != test.permitted {
+			t.Fatalf("Expected %q permitted=%v with allowed %v and denied %v\n", test.value, test.permitted, test.allowed, test.denied)
+		}
+	}
+}
+
+func TestConstraints_Roles(t *testing.T) {
+	constraints := map[string]interface{}{
+		"denied_usernames": "admin",
+		"allowed_groups":   "team-a-*",
+		"denied_groups":    "*-admins",
+		"allowed_scopes":   "member-of-groups:team-a-*",
+		"max_ttl":          "1h",
+
+		"allowed_permission_targets": "team-a-*",
+		"denied_repositories":        "*-release",
+		"denied_actions":             "delete",
+	}
+
+	tests := []struct {
+		expectation Expectation
+		role        map[string]interface{}
+	}{
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"member_of_groups": "team-a-readers", "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"username": "admin", "member_of_groups": "team-a-readers", "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"member_of_groups": "team-a-readers,readers", "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"member_of_groups": "team-a-admins", "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"member_of_groups": "team-a-readers", "ttl": "2h"},
+		},
+		{
+			FailWithLogicalError, // Tokens would never expire
+			map[string]interface{}{"member_of_groups": "team-a-readers"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"username": "user", "allow_all_groups": true, "ttl": "30m"},
+		},
+		{
+			ExpectedToSucceed, // The lease's group is not in the checked scope
+			map[string]interface{}{"group_template": `{"permission_targets": {"team-a-libs": ["read"]}}`, "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"group_template": `{"permission_targets": {"team-b-libs": ["read"]}}`, "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"group_template": `{"permission_targets": {"team-a-libs": ["read", "delete"]}}`, "ttl": "30m"},
+		},
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"member_of_groups": "team-a-readers", "user_mode": "dynamic", "permission_repositories": "team-a-snapshot", "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"member_of_groups": "team-a-readers", "user_mode": "dynamic", "permission_repositories": "team-a-release", "ttl": "30m"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"member_of_groups": "team-a-readers", "user_mode": "dynamic", "permission_repositories": "team-a-snapshot", "permission_actions": "read,delete", "ttl": "30m"},
+		},
+	}
+
+	for _, test := range tests {
+		b, storage := newBackend(t)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/constraints",
+			Storage:   storage,
+			Data:      constraints,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test",
+			Storage:   storage,
+			Data:      test.role,
+		})
+		assertLogicalResponse(t, test.expectation, err, resp)
+		if test.expectation == FailWithLogicalError && resp == nil {
+			t.Fatalf("Expected role %v to violate constraints\n", test.role)
+		}
+	}
+}
+
+func TestConstraints_Scope(t *testing.T) {
+	c := &constraintsConfig{AllowedScopes: []string{"member-of-groups:team-*"}}
+
+	tests := []struct {
+		scope     string
+		permitted bool
+	}{
+		{"member-of-groups:team-a", true},
+		{"member-of-groups:team-a,team-b", true},
+		{"member-of-groups:team-a,admins", false},
+		{"member-of-groups:admins,team-a", false},
+		{"member-of-groups:*", false},
+		{"api:*", false},
+	}
+
+	for _, test := range tests {
+		if err := c.checkScope(test.scope); (err == nil) != test.permitted {
+			t.Fatalf("Expected scope %q permitted=%v, got: %v\n", test.scope, test.permitted, err)
+		}
+	}
+
+	// Groups are not otherwise constrained, so only the scope rejects the role
+	b, storage := newBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/constraints",
+		Storage:   storage,
+		Data:      map[string]interface{}{"allowed_scopes": "member-of-groups:team-*"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"member_of_groups": "team-a,admins"},
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+	if resp == nil {
+		t.Fatal("Expected member-of-groups:team-a,admins to violate allowed_scopes\n")
+	}
+}
+
+func TestConstraints_Token(t *testing.T) {
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address": "https://example.com/artifactory/",
+			"api_key": "abc123",
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"member_of_groups": "readers",
+			"skip_validation":  true,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// Tightening the constraints applies to the existing role
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/constraints",
+		Storage:   storage,
+		Data:      map[string]interface{}{"allowed_groups": "team-a-*"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+	if resp == nil {
+		t.Fatal("Expected token to be refused")
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/constraints",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if groups := resp.Data["allowed_groups"].([]string); len(groups) != 1 || groups[0] != "team-a-*" {
+		t.Fatalf("Expected allowed_groups to be stored, got: %v\n", resp.Data)
+	}
+}
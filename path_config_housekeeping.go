@@ -0,0 +1,185 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	defaultTidyInterval            = time.Hour
+	defaultIssuanceCleanupInterval = 24 * time.Hour
+	defaultCredentialCheckInterval = 24 * time.Hour
+	defaultCredentialExpiryWarning = 7 * 24 * time.Hour
+	defaultAccessTokenRotation     = time.Duration(0)
+	defaultRoleHistoryVersions     = 10
+	defaultRoleHistoryCleanup      = 24 * time.Hour
+	defaultDeletedRoleRetention    = 30 * 24 * time.Hour
+)
+
+func pathConfigHousekeeping(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/housekeeping",
+		Fields: map[string]*framework.FieldSchema{
+			"tidy_interval": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Interval between revoking tokens whose lease ended without being revoked. Set to 0 to disable.",
+				Default:     int(defaultTidyInterval.Seconds()),
+			},
+			"issuance_cleanup_interval": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Interval between removing issuance records of expired leases. Set to 0 to disable.",
+				Default:     int(defaultIssuanceCleanupInterval.Seconds()),
+			},
+			"credential_check_interval": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Interval between checking the expiry of the configured access_token. Set to 0 to disable.",
+				Default:     int(defaultCredentialCheckInterval.Seconds()),
+			},
+			"credential_expiry_warning": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long before the configured access_token expires to start warning.",
+				Default:     int(defaultCredentialExpiryWarning.Seconds()),
+			},
+			"access_token_rotation_interval": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Interval between replacing the configured access_token with a new token for the same user and scope. Disabled if 0.",
+				Default:     int(defaultAccessTokenRotation.Seconds()),
+			},
+			"role_history_versions": {
+				Type:        framework.TypeInt,
+				Description: "Number of versions kept in the history of each role.",
+				Default:     defaultRoleHistoryVersions,
+			},
+			"role_history_cleanup_interval": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Interval between removing the history of roles deleted longer than deleted_role_history_retention ago. Set to 0 to disable.",
+				Default:     int(defaultRoleHistoryCleanup.Seconds()),
+			},
+			"deleted_role_history_retention": {
+				Type:        framework.TypeDurationSecond,
+				Description: "How long the history of a deleted role is kept, during which the role can be restored.",
+				Default:     int(defaultDeletedRoleRetention.Seconds()),
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigHousekeepingRead,
+			logical.UpdateOperation: b.pathConfigHousekeepingWrite,
+		},
+		HelpSynopsis: pathConfigHousekeepingHelpSyn,
+	}
+}
+
+func (b *backend) readHousekeepingConfig(ctx context.Context, storage logical.Storage) (*housekeepingConfig, error) {
+	entry, err := storage.Get(ctx, "config/housekeeping")
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &housekeepingConfig{
+		TidyInterval:            defaultTidyInterval,
+		IssuanceCleanupInterval: defaultIssuanceCleanupInterval,
+		CredentialCheckInterval: defaultCredentialCheckInterval,
+		CredentialExpiryWarning: defaultCredentialExpiryWarning,
+		AccessTokenRotation:     defaultAccessTokenRotation,
+		RoleHistoryVersions:     defaultRoleHistoryVersions,
+		RoleHistoryCleanup:      defaultRoleHistoryCleanup,
+		DeletedRoleRetention:    defaultDeletedRoleRetention,
+	}
+	if entry == nil {
+		return conf, nil
+	}
+
+	if err := entry.DecodeJSON(conf); err != nil {
+		return nil, fmt.Errorf("error reading housekeeping configuration: %v", err)
+	}
+
+	return conf, nil
+}
+
+func (b *backend) pathConfigHousekeepingRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readHousekeepingConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"tidy_interval":                  int64(conf.TidyInterval.Seconds()),
+			"issuance_cleanup_interval":      int64(conf.IssuanceCleanupInterval.Seconds()),
+			"credential_check_interval":      int64(conf.CredentialCheckInterval.Seconds()),
+			"credential_expiry_warning":      int64(conf.CredentialExpiryWarning.Seconds()),
+			"access_token_rotation_interval": int64(conf.AccessTokenRotation.Seconds()),
+			"role_history_versions":          conf.RoleHistoryVersions,
+			"role_history_cleanup_interval":  int64(conf.RoleHistoryCleanup.Seconds()),
+			"deleted_role_history_retention": int64(conf.DeletedRoleRetention.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigHousekeepingWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readHousekeepingConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if tidyInterval, ok := data.GetOk("tidy_interval"); ok {
+		conf.TidyInterval = time.Duration(tidyInterval.(int)) * time.Second
+	}
+	if cleanupInterval, ok := data.GetOk("issuance_cleanup_interval"); ok {
+		conf.IssuanceCleanupInterval = time.Duration(cleanupInterval.(int)) * time.Second
+	}
+	if checkInterval, ok := data.GetOk("credential_check_interval"); ok {
+		conf.CredentialCheckInterval = time.Duration(checkInterval.(int)) * time.Second
+	}
+	if expiryWarning, ok := data.GetOk("credential_expiry_warning"); ok {
+		conf.CredentialExpiryWarning = time.Duration(expiryWarning.(int)) * time.Second
+	}
+	if rotationInterval, ok := data.GetOk("access_token_rotation_interval"); ok {
+		conf.AccessTokenRotation = time.Duration(rotationInterval.(int)) * time.Second
+	}
+	if versions, ok := data.GetOk("role_history_versions"); ok {
+		conf.RoleHistoryVersions = versions.(int)
+	}
+	if cleanupInterval, ok := data.GetOk("role_history_cleanup_interval"); ok {
+		conf.RoleHistoryCleanup = time.Duration(cleanupInterval.(int)) * time.Second
+	}
+	if retention, ok := data.GetOk("deleted_role_history_retention"); ok {
+		conf.DeletedRoleRetention = time.Duration(retention.(int)) * time.Second
+	}
+	if conf.TidyInterval < 0 || conf.IssuanceCleanupInterval < 0 || conf.CredentialCheckInterval < 0 || conf.CredentialExpiryWarning < 0 ||
+		conf.AccessTokenRotation < 0 || conf.RoleHistoryCleanup < 0 || conf.DeletedRoleRetention < 0 {
+		return logical.ErrorResponse("intervals cannot be negative"), nil
+	}
+	if conf.RoleHistoryVersions < 1 {
+		return logical.ErrorResponse("role_history_versions must be at least 1"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config/housekeeping", conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type housekeepingConfig struct {
+	TidyInterval            time.Duration `json:"tidy_interval"`
+	IssuanceCleanupInterval time.Duration `json:"issuance_cleanup_interval"`
+	CredentialCheckInterval time.Duration `json:"credential_check_interval"`
+	CredentialExpiryWarning time.Duration `json:"credential_expiry_warning"`
+	AccessTokenRotation     time.Duration `json:"access_token_rotation_interval"`
+	RoleHistoryVersions     int           `json:"role_history_versions"`
+	RoleHistoryCleanup      time.Duration `json:"role_history_cleanup_interval"`
+	DeletedRoleRetention    time.Duration `json:"deleted_role_history_retention"`
+}
+
+const pathConfigHousekeepingHelpSyn = `
+Configure how often the background housekeeping tasks run.
+`
@@ -0,0 +1,83 @@
+package artifactory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestConfigHousekeeping_Lifecycle(t *testing.T) {
+	b, storage := newBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/housekeeping",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if resp.Data["tidy_interval"].(int64) != int64(defaultTidyInterval.Seconds()) {
+		t.Fatalf("Expected default tidy_interval, got: %v\n", resp.Data)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/housekeeping",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"tidy_interval": "0",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/housekeeping",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if resp.Data["tidy_interval"].(int64) != 0 {
+		t.Fatalf("tidy_interval not updated, expected 0, got: %v\n", resp.Data)
+	}
+	if resp.Data["issuance_cleanup_interval"].(int64) != int64((24 * time.Hour).Seconds()) {
+		t.Fatalf("issuance_cleanup_interval should be unchanged, got: %v\n", resp.Data)
+	}
+	if resp.Data["credential_check_interval"].(int64) != int64(defaultCredentialCheckInterval.Seconds()) {
+		t.Fatalf("credential_check_interval should be unchanged, got: %v\n", resp.Data)
+	}
+	if resp.Data["role_history_versions"].(int) != defaultRoleHistoryVersions {
+		t.Fatalf("role_history_versions should be unchanged, got: %v\n", resp.Data)
+	}
+	if resp.Data["deleted_role_history_retention"].(int64) != int64(defaultDeletedRoleRetention.Seconds()) {
+		t.Fatalf("deleted_role_history_retention should be unchanged, got: %v\n", resp.Data)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/housekeeping",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"issuance_cleanup_interval": "-1",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+
+	req.Data = map[string]interface{}{
+		"credential_expiry_warning": "-1",
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+
+	req.Data = map[string]interface{}{
+		"role_history_versions": 0,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+}
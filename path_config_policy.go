@@ -0,0 +1,98 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathConfigPolicy(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/policy",
+		Fields: map[string]*framework.FieldSchema{
+			"forbid_all_groups": {
+				Type:        framework.TypeBool,
+				Description: "Reject roles which set allow_all_groups, issuing tokens with all groups of the user.",
+			},
+			"forbid_admin_scopes": {
+				Type:        framework.TypeBool,
+				Description: "Reject roles whose tokens would carry admin privileges through their groups or user.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigPolicyRead,
+			logical.UpdateOperation: b.pathConfigPolicyWrite,
+		},
+		HelpSynopsis: pathConfigPolicyHelpSyn,
+	}
+}
+
+func (b *backend) readPolicyConfig(ctx context.Context, storage logical.Storage) (*policyConfig, error) {
+	entry, err := storage.Get(ctx, "config/policy")
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &policyConfig{}
+	if entry == nil {
+		return conf, nil
+	}
+
+	if err := entry.DecodeJSON(conf); err != nil {
+		return nil, fmt.Errorf("error reading policy configuration: %v", err)
+	}
+
+	return conf, nil
+}
+
+func (b *backend) pathConfigPolicyRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readPolicyConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"forbid_all_groups":   conf.ForbidAllGroups,
+			"forbid_admin_scopes": conf.ForbidAdminScopes,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigPolicyWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	conf, err := b.readPolicyConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if forbidAllGroups, ok := data.GetOk("forbid_all_groups"); ok {
+		conf.ForbidAllGroups = forbidAllGroups.(bool)
+	}
+	if forbidAdminScopes, ok := data.GetOk("forbid_admin_scopes"); ok {
+		conf.ForbidAdminScopes = forbidAdminScopes.(bool)
+	}
+
+	entry, err := logical.StorageEntryJSON("config/policy", conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// policyConfig restricts the roles which can be written and which tokens are
+// issued. Roles written before the policy are checked again on each
+// issuance, and roles/audit reports those which violate it.
+type policyConfig struct {
+	ForbidAllGroups   bool `json:"forbid_all_groups"`
+	ForbidAdminScopes bool `json:"forbid_admin_scopes"`
+}
+
+const pathConfigPolicyHelpSyn = `
+Configure which token scopes roles are allowed to grant.
+`
@@ -0,0 +1,298 @@
+package artifactory
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// newPolicyServer serves a user and groups, some with admin privileges.
+func newPolicyServer(t *testing.T) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/security/groups/":
+			w.Write([]byte(`[{"name": "readers"}, {"name": "admins"}]`))
+		case "/api/security/groups/readers":
+			w.Write([]byte(`{"name": "readers", "adminPrivileges": false}`))
+		case "/api/security/groups/admins":
+			w.Write([]byte(`{"name": "admins", "adminPrivileges": true}`))
+		case "/api/security/users/admin":
+			w.Write([]byte(`{"name": "admin", "admin": true}`))
+		case "/api/security/users/user":
+			w.Write([]byte(`{"name": "user", "admin": false}`))
+		default:
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+	}))
+}
+
+func TestConfigPolicy_Roles(t *testing.T) {
+	ts := newPolicyServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		expectation Expectation
+		policy      map[string]interface{}
+		role        map[string]interface{}
+	}{
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{},
+			map[string]interface{}{"username": "admin", "allow_all_groups": true},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"forbid_all_groups": true},
+			map[string]interface{}{"username": "user", "allow_all_groups": true},
+		},
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"forbid_all_groups": true},
+			map[string]interface{}{"member_of_groups": "admins"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"forbid_admin_scopes": true},
+			map[string]interface{}{"member_of_groups": "readers,admins"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"forbid_admin_scopes": true},
+			map[string]interface{}{"username": "admin", "allow_all_groups": true},
+		},
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"forbid_admin_scopes": true},
+			map[string]interface{}{"username": "user", "allow_all_groups": true},
+		},
+		{
+			ExpectedToSucceed, // The admin's privileges are limited to the groups
+			map[string]interface{}{"forbid_admin_scopes": true},
+			map[string]interface{}{"username": "admin", "member_of_groups": "readers"},
+		},
+	}
+
+	for _, test := range tests {
+		b, storage := newBackend(t)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"address":    ts.URL + "/",
+				"api_key":    "abc123",
+				"tls_verify": false,
+			},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/policy",
+			Storage:   storage,
+			Data:      test.policy,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test",
+			Storage:   storage,
+			Data:      test.role,
+		})
+		assertLogicalResponse(t, test.expectation, err, resp)
+		if test.expectation == FailWithLogicalError && resp == nil {
+			t.Fatalf("Expected role %v to violate policy %v\n", test.role, test.policy)
+		}
+	}
+}
+
+func TestRoles_Audit(t *testing.T) {
+	ts := newPolicyServer(t)
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	roles := map[string]map[string]interface{}{
+		"readers":     {"member_of_groups": "readers"},
+		"admins":      {"member_of_groups": "admins"},
+		"user-scoped": {"username": "user", "allow_all_groups": true},
+	}
+	for name, data := range roles {
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/" + name,
+			Storage:   storage,
+			Data:      data,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+
+	// Existing roles are reported, not rejected, once the policy changes
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/policy",
+		Storage:   storage,
+		Data:      map[string]interface{}{"forbid_admin_scopes": true},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/audit",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	audited := resp.Data["roles"].(map[string]interface{})
+	if len(audited) != 2 {
+		t.Fatalf("Expected 2 roles to be reported, got: %v\n", audited)
+	}
+	admins := audited["admins"].(map[string]interface{})
+	if !admins["admin"].(bool) || len(admins["violations"].([]string)) != 1 {
+		t.Fatalf("Expected admins role to violate policy, got: %v\n", admins)
+	}
+	userScoped := audited["user-scoped"].(map[string]interface{})
+	if !userScoped["all_groups"].(bool) || len(userScoped["violations"].([]string)) != 0 {
+		t.Fatalf("Expected user-scoped role to be reported without violations, got: %v\n", userScoped)
+	}
+}
+
+func TestConfigPolicy_Token(t *testing.T) {
+	ts := newPolicyServer(t)
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/policy",
+		Storage:   storage,
+		Data:      map[string]interface{}{"forbid_admin_scopes": true},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// Admin privileges cannot be checked before the engine is configured
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"member_of_groups": "admins"},
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/policy",
+		Storage:   storage,
+		Data:      map[string]interface{}{"forbid_admin_scopes": false},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"member_of_groups": "admins"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// The policy is tightened after the role was written
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/policy",
+		Storage:   storage,
+		Data:      map[string]interface{}{"forbid_admin_scopes": true},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+	if resp == nil {
+		t.Fatal("Expected token to be refused for a role which violates the policy\n")
+	}
+}
+
+func TestConfigPolicy_AdminCache(t *testing.T) {
+	lookups := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		w.Write([]byte(`{"name": "readers", "adminPrivileges": false}`))
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	role := &roleConfig{MemberOfGroups: []string{"readers"}}
+	for i := 0; i < 2; i++ {
+		auditor, err := b.(*backend).newScopeAuditor(ctx, storage)
+		if err != nil {
+			t.Fatalf("Failed to create auditor: %v\n", err)
+		}
+		if _, err := auditor.audit(role); err != nil {
+			t.Fatalf("Failed to audit role: %v\n", err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("Expected the group to be looked up once, got %d lookups\n", lookups)
+	}
+
+	// Changing the config discards the cache
+	b.(*backend).resetClient()
+	auditor, err := b.(*backend).newScopeAuditor(ctx, storage)
+	if err != nil {
+		t.Fatalf("Failed to create auditor: %v\n", err)
+	}
+	if _, err := auditor.audit(role); err != nil {
+		t.Fatalf("Failed to audit role: %v\n", err)
+	}
+	if lookups != 2 {
+		t.Fatalf("Expected the group to be looked up again, got %d lookups\n", lookups)
+	}
+}
@@ -59,6 +59,37 @@ func TestConfig_Write(t *testing.T) {
 				"password": "password",
 			},
 		},
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{
+				"address":      "https://example.com/artifactory",
+				"access_token": "abc123",
+				"project_key":  "ci",
+			},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{
+				"address":      "https://example.com/artifactory",
+				"access_token": "abc123",
+				"api_key":      "abc123",
+			},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{
+				"address":      "https://example.com/artifactory",
+				"access_token": "abc123",
+				"project_key":  "Invalid Project",
+			},
+		},
+		{
+			FailWithLogicalError, // The payload is not base64
+			map[string]interface{}{
+				"address":      "https://example.com/artifactory",
+				"access_token": "header.!.signature",
+			},
+		},
 		{FailWithLogicalError, map[string]interface{}{"address": "https://example.com/artifactory"}},
 		{FailWithLogicalError, map[string]interface{}{"api_key": "abc123"}},
 		{FailWithLogicalError, map[string]interface{}{}},
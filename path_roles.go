@@ -3,15 +3,70 @@ package artifactory
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// Role names are one or more segments, each like framework.GenericNameRegex,
+// separated by /, e.g. teamA/ci/reader. This lets ACL policies grant access to
+// a team's roles with a path glob.
+const roleNameSegmentRegex = `\w(([\w-.]+)?\w)?`
+
+func roleNameRegex(name string) string {
+	return fmt.Sprintf(`(?P<%s>%s(/%s)*)`, name, roleNameSegmentRegex, roleNameSegmentRegex)
+}
+
 func pathListRoles(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "roles/?$",
+		Pattern: "roles/?(?P<prefix>(" + roleNameSegmentRegex + "/)*)$",
+		Fields: map[string]*framework.FieldSchema{
+			"prefix": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Namespace of the roles to list, e.g. teamA/.",
+			},
+
+			"tag": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Only list roles with all of these tags.",
+				Query:       true,
+			},
+
+			"group": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Only list roles scoping tokens to all of these groups.",
+				Query:       true,
+			},
+
+			"username_prefix": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Only list roles with a username starting with this prefix.",
+				Query:       true,
+			},
+
+			"metadata": &framework.FieldSchema{
+				Type:        framework.TypeKVPairs,
+				Description: "Only list roles with these metadata values, e.g. team=platform.",
+				Query:       true,
+			},
+
+			"after": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Only list roles whose name sorts after this one, to continue a paginated listing.",
+				Query:       true,
+			},
+
+			"limit": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Description: "Maximum number of roles to list. Unlimited if 0.",
+				Query:       true,
+			},
+		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ListOperation: b.pathRoleList,
@@ -21,27 +76,115 @@ func pathListRoles(b *backend) *framework.Path {
 
 func pathRoles(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Pattern: "roles/" + roleNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": &framework.FieldSchema{
 				Type:        framework.TypeString,
 				Description: "Name of the role",
 			},
 
+			"description": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Human readable description of the role.",
+			},
+
+			"tags": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Tags roles can be listed by.",
+			},
+
+			"metadata": &framework.FieldSchema{
+				Type:        framework.TypeKVPairs,
+				Description: "Free-form key/values recorded with the role and each lease, e.g. team=platform.",
+			},
+
 			"username": &framework.FieldSchema{
 				Type:        framework.TypeString,
 				Description: "User name of the created access token",
 			},
 
+			"user_mode": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "How users are provided when no username is set. transient relies on Artifactory creating a transient user, dynamic creates a user for each lease and deletes it when the lease is revoked.",
+				Default:     userModeTransient,
+			},
+
 			"member_of_groups": &framework.FieldSchema{
 				Type:        framework.TypeCommaStringSlice,
 				Description: "List of groups that the token is associated with.",
 			},
 
+			"allow_all_groups": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Issue tokens with all groups of the user when member_of_groups is empty or *. Requires a username.",
+			},
+
+			"permission_repositories": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Repository keys, or ANY, ANY LOCAL and ANY REMOTE, of a permission target created for each lease. Requires user_mode dynamic.",
+			},
+
+			"permission_actions": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Actions granted by the permission target created for each lease. Supported actions: read, deploy, delete, annotate.",
+				Default:     []string{"read"},
+			},
+
+			"permission_include_patterns": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Path patterns the permission target applies to.",
+				Default:     []string{"**"},
+			},
+
+			"permission_exclude_patterns": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Path patterns the permission target does not apply to.",
+			},
+
+			"group_template": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: `JSON template of a group created for each lease, e.g. {"description": "...", "permission_targets": {"libs": ["read"]}}. The group is granted the repositories of the existing permission targets through permission targets created for the lease, which are deleted with the group when the lease is revoked.`,
+			},
+
+			"project_key": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Key of the JFrog project tokens are issued for. Defaults to the project_key of the config.",
+			},
+
+			"include_reference_token": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Also issue a short reference token for the access token, for clients which cannot use a full JWT.",
+			},
+
+			"description_template": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Go text/template rendered as the description of each access token, e.g. vault {{.RoleName}} for {{.DisplayName}}.",
+			},
+
+			"skip_validation": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Write the role without checking that member_of_groups exist in Artifactory.",
+			},
+
 			"ttl": &framework.FieldSchema{
 				Type:        framework.TypeDurationSecond,
 				Description: "TTL for the access token created from the role.",
 			},
+
+			"docker_registry": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Docker registry host used when rendering tokens in the docker format. Defaults to the Artifactory host.",
+			},
+
+			"repositories": &framework.FieldSchema{
+				Type:        framework.TypeKVPairs,
+				Description: "Repository keys used when rendering tokens in package manager formats, keyed by format, e.g. npm=npm-virtual.",
+			},
+
+			"output_template": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Go text/template rendered with the created access token and returned in the rendered field.",
+			},
 		},
 
 		ExistenceCheck: b.operationRoleExistenceCheck,
@@ -55,12 +198,141 @@ func pathRoles(b *backend) *framework.Path {
 }
 
 func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	entries, err := req.Storage.List(ctx, "role/")
+	filter := &roleFilter{
+		Tags:           d.Get("tag").([]string),
+		Groups:         d.Get("group").([]string),
+		UsernamePrefix: d.Get("username_prefix").(string),
+		Metadata:       d.Get("metadata").(map[string]string),
+	}
+	after := d.Get("after").(string)
+	limit := d.Get("limit").(int)
+	if limit < 0 {
+		return logical.ErrorResponse("limit cannot be negative"), nil
+	}
+
+	conf, err := b.readConfig(ctx, req.Storage)
 	if err != nil {
 		return nil, err
 	}
+	var instance string
+	if conf != nil {
+		instance = conf.Address
+	}
 
-	return logical.ListResponse(entries), nil
+	prefix := d.Get("prefix").(string)
+	entries, err := req.Storage.List(ctx, "role/"+prefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(entries)
+
+	var keys []string
+	keyInfo := map[string]interface{}{}
+	for _, name := range entries {
+		if name <= after {
+			continue
+		}
+		if limit > 0 && len(keys) == limit {
+			break
+		}
+
+		// Namespaces are listed so that they can be navigated, the filters
+		// apply to the roles within them when they are listed
+		if strings.HasSuffix(name, "/") {
+			keys = append(keys, name)
+			continue
+		}
+
+		role, err := readRole(ctx, req.Storage, prefix+name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil || !filter.matches(role) {
+			continue
+		}
+
+		keys = append(keys, name)
+		keyInfo[name] = map[string]interface{}{
+			"description":      role.Description,
+			"username":         role.Username,
+			"member_of_groups": role.MemberOfGroups,
+			"ttl":              int64(role.TTL.Seconds()),
+			"instance":         instance,
+			"project_key":      role.ProjectKey,
+			"tags":             role.Tags,
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+// roleFilter selects the roles to list, every condition set must match.
+type roleFilter struct {
+	Tags           []string
+	Groups         []string
+	UsernamePrefix string
+	Metadata       map[string]string
+}
+
+func (f *roleFilter) matches(role *roleConfig) bool {
+	for _, tag := range f.Tags {
+		if !strutil.StrListContains(role.Tags, tag) {
+			return false
+		}
+	}
+	// Group names are case insensitive
+	for _, group := range f.Groups {
+		found := false
+		for _, roleGroup := range role.MemberOfGroups {
+			if strings.EqualFold(roleGroup, group) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.UsernamePrefix != "" && !strings.HasPrefix(role.Username, f.UsernamePrefix) {
+		return false
+	}
+	for k, v := range f.Metadata {
+		if value, ok := role.Metadata[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// listRoleNames returns the full names of every role, within namespaces.
+func listRoleNames(ctx context.Context, s logical.Storage) ([]string, error) {
+	return listNamespacedKeys(ctx, s, "role/")
+}
+
+// listNamespacedKeys returns every key under root, descending into the
+// namespaces of role names.
+func listNamespacedKeys(ctx context.Context, s logical.Storage, root string) ([]string, error) {
+	var names []string
+	prefixes := []string{""}
+	for len(prefixes) > 0 {
+		prefix := prefixes[len(prefixes)-1]
+		prefixes = prefixes[:len(prefixes)-1]
+
+		entries, err := s.List(ctx, root+prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if strings.HasSuffix(entry, "/") {
+				prefixes = append(prefixes, prefix+entry)
+			} else {
+				names = append(names, prefix+entry)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
 }
 
 func readRole(ctx context.Context, s logical.Storage, name string) (*roleConfig, error) {
@@ -76,10 +348,23 @@ func readRole(ctx context.Context, s logical.Storage, name string) (*roleConfig,
 	if err := raw.DecodeJSON(role); err != nil {
 		return nil, err
 	}
+	if _, err := upgradeRole(raw, role); err != nil {
+		return nil, err
+	}
 
 	return role, nil
 }
 
+func writeRole(ctx context.Context, s logical.Storage, name string, role *roleConfig) error {
+	role.Version = roleStorageVersion
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
 func (b *backend) pathRolesRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
 	if name == "" {
@@ -94,15 +379,39 @@ func (b *backend) pathRolesRead(ctx context.Context, req *logical.Request, d *fr
 		return nil, nil
 	}
 
-	// Generate the response
-	resp := &logical.Response{
-		Data: map[string]interface{}{
-			"username":         role.Username,
-			"member_of_groups": role.MemberOfGroups,
-			"ttl":              int64(role.TTL.Seconds()),
-		},
+	data := roleResponseData(role)
+	data["role_version"] = role.RoleVersion
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+// roleResponseData returns the fields of role as they are read and written.
+// It can be written back as is, so excludes the role_version.
+func roleResponseData(role *roleConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"description":      role.Description,
+		"tags":             role.Tags,
+		"metadata":         role.Metadata,
+		"username":         role.Username,
+		"user_mode":        role.UserMode,
+		"member_of_groups": role.MemberOfGroups,
+		"allow_all_groups": role.AllowAllGroups,
+		"ttl":              int64(role.TTL.Seconds()),
+		"docker_registry":  role.DockerRegistry,
+		"repositories":     role.Repositories,
+		"output_template":  role.OutputTemplate,
+
+		"permission_repositories":     role.PermissionRepositories,
+		"permission_actions":          role.PermissionActions,
+		"permission_include_patterns": role.PermissionIncludePatterns,
+		"permission_exclude_patterns": role.PermissionExcludePatterns,
+		"group_template":              role.GroupTemplate,
+		"project_key":                 role.ProjectKey,
+		"include_reference_token":     role.IncludeReferenceToken,
+		"description_template":        role.DescriptionTemplate,
 	}
-	return resp, nil
 }
 
 func (b *backend) operationRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
@@ -119,62 +428,350 @@ func (b *backend) pathRolesCreateUpdate(ctx context.Context, req *logical.Reques
 		return logical.ErrorResponse("missing role name"), nil
 	}
 
-	// Check if the role already exists
+	if reason := reservedRoleName(roleName); reason != "" {
+		return logical.ErrorResponse(reason), nil
+	}
+
+	lock := b.roleLock(roleName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The existence check ran before the lock was held, so whether the role
+	// is created or updated is decided by what is stored now
 	role, err := readRole(ctx, req.Storage, roleName)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a new entry object if this is a CreateOperation
+	var op logical.Operation = logical.UpdateOperation
 	if role == nil {
 		if req.Operation == logical.UpdateOperation {
 			return nil, errors.New("role entry not found during update operation")
 		}
 		role = new(roleConfig)
+		op = logical.CreateOperation
+	}
+	operation := roleChangeUpdate
+	if op == logical.CreateOperation {
+		operation = roleChangeCreate
+	}
+
+	if resp, err := b.updateRole(ctx, req.Storage, role, op, d, nil); resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := b.recordRoleVersion(ctx, req.Storage, roleName, role, req.EntityID, operation); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// reservedRoleName returns why name cannot be used for a role, or "" if it
+// can. These names would be shadowed by the paths of other endpoints.
+func reservedRoleName(name string) string {
+	if name == roleAuditName {
+		return fmt.Sprintf("role name %q is reserved", roleAuditName)
+	}
+	segments := strings.Split(name, "/")
+	switch last := segments[len(segments)-1]; last {
+	case roleHistoryName, roleRollbackName:
+		return fmt.Sprintf("role names cannot end in %q", last)
+	}
+	return ""
+}
+
+// buildRole creates a role from data, the fields of a roles/:name write. A
+// reason is returned if the role is invalid. Groups are validated against
+// known, which may be nil.
+func (b *backend) buildRole(ctx context.Context, s logical.Storage, data map[string]interface{}, known *knownGroups) (*roleConfig, string, error) {
+	fields := pathRoles(b).Fields
+	for k := range data {
+		// Unknown fields are most likely typos, which would otherwise be
+		// silently ignored
+		if _, ok := fields[k]; !ok || k == "name" {
+			return nil, fmt.Sprintf("unknown field %q", k), nil
+		}
+	}
+
+	d := &framework.FieldData{
+		Raw:    data,
+		Schema: fields,
+	}
+	if err := d.Validate(); err != nil {
+		return nil, err.Error(), nil
+	}
+
+	role := new(roleConfig)
+	resp, err := b.updateRole(ctx, s, role, logical.CreateOperation, d, known)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp != nil && resp.IsError() {
+		return nil, resp.Error().Error(), nil
+	}
+	return role, "", nil
+}
+
+// updateRole sets the fields of role given in d and validates the result.
+// Defaults are applied for a CreateOperation. A response is returned if the
+// role is invalid. Groups are validated against known, which may be nil.
+func (b *backend) updateRole(ctx context.Context, s logical.Storage, role *roleConfig, op logical.Operation, d *framework.FieldData, known *knownGroups) (*logical.Response, error) {
+	if description, ok := d.GetOk("description"); ok {
+		role.Description = description.(string)
+	}
+	if tags, ok := d.GetOk("tags"); ok {
+		role.Tags = strutil.RemoveDuplicates(tags.([]string), false)
+	}
+	if metadata, ok := d.GetOk("metadata"); ok {
+		role.Metadata = metadata.(map[string]string)
+	}
+	for k := range role.Metadata {
+		if k == "" {
+			return logical.ErrorResponse("metadata keys cannot be empty"), nil
+		}
 	}
 
 	if username, ok := d.GetOk("username"); ok {
 		role.Username = username.(string)
 	}
+	previousGroups := role.MemberOfGroups
 	if memberOfGroups, ok := d.GetOk("member_of_groups"); ok {
 		role.MemberOfGroups = memberOfGroups.([]string)
 	}
-	if len(role.MemberOfGroups) == 0 {
+	if allowAllGroups, ok := d.GetOk("allow_all_groups"); ok {
+		role.AllowAllGroups = allowAllGroups.(bool)
+	}
+
+	if repositories, ok := d.GetOk("permission_repositories"); ok {
+		role.PermissionRepositories = repositories.([]string)
+	}
+	if actions, ok := d.GetOk("permission_actions"); ok {
+		role.PermissionActions = actions.([]string)
+	} else if op == logical.CreateOperation {
+		role.PermissionActions = d.Get("permission_actions").([]string)
+	}
+	if patterns, ok := d.GetOk("permission_include_patterns"); ok {
+		role.PermissionIncludePatterns = patterns.([]string)
+	} else if op == logical.CreateOperation {
+		role.PermissionIncludePatterns = d.Get("permission_include_patterns").([]string)
+	}
+	if patterns, ok := d.GetOk("permission_exclude_patterns"); ok {
+		role.PermissionExcludePatterns = patterns.([]string)
+	}
+	for _, action := range role.PermissionActions {
+		if _, ok := permissionActions[action]; !ok {
+			return logical.ErrorResponse(fmt.Sprintf("permission_actions contains unsupported action %q", action)), nil
+		}
+	}
+
+	if groupTemplate, ok := d.GetOk("group_template"); ok {
+		role.GroupTemplate = groupTemplate.(string)
+	}
+	if role.GroupTemplate != "" {
+		if _, err := parseGroupTemplate(role.GroupTemplate); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid group_template: %v", err)), nil
+		}
+		// Fixed users are shared by every lease, so cannot have their
+		// membership isolated to one
+		if role.Username != "" {
+			return logical.ErrorResponse("username cannot be set with group_template"), nil
+		}
+	}
+
+	// A role granting a permission target or dynamic group may rely on it
+	// alone
+	if len(role.MemberOfGroups) == 0 && len(role.PermissionRepositories) == 0 && role.GroupTemplate == "" {
 		if role.Username == "" {
 			return logical.ErrorResponse("member_of_groups cannot be empty if no username supplied"), nil
 		}
-		// Assume user-scoped-token
-		// This will fail when creating tokens if the username is transient
+		if !role.AllowAllGroups {
+			return logical.ErrorResponse("member_of_groups cannot be empty unless allow_all_groups is set"), nil
+		}
+		// User-scoped-token
 		role.MemberOfGroups = []string{"*"}
 	}
+	if strutil.StrListContains(role.MemberOfGroups, "*") && !role.AllowAllGroups {
+		return logical.ErrorResponse("member_of_groups can only contain * when allow_all_groups is set"), nil
+	}
+
+	if userMode, ok := d.GetOk("user_mode"); ok {
+		role.UserMode = userMode.(string)
+	} else if op == logical.CreateOperation {
+		role.UserMode = d.Get("user_mode").(string)
+	}
+	switch role.UserMode {
+	case userModeTransient:
+	case userModeDynamic:
+		if role.Username != "" {
+			return logical.ErrorResponse("username cannot be set when user_mode is dynamic"), nil
+		}
+		if strutil.StrListContains(role.MemberOfGroups, "*") {
+			return logical.ErrorResponse("member_of_groups must list the groups of the user when user_mode is dynamic"), nil
+		}
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported user_mode %q", role.UserMode)), nil
+	}
+	if len(role.PermissionRepositories) > 0 {
+		// Permission targets can only grant access to existing users
+		if role.UserMode != userModeDynamic {
+			return logical.ErrorResponse("permission_repositories requires user_mode dynamic"), nil
+		}
+		if len(role.PermissionActions) == 0 {
+			return logical.ErrorResponse("permission_actions cannot be empty if permission_repositories supplied"), nil
+		}
+	}
 
 	if tokenTTLRaw, ok := d.GetOk("ttl"); ok {
 		role.TTL = time.Duration(tokenTTLRaw.(int)) * time.Second
-	} else if req.Operation == logical.CreateOperation {
+	} else if op == logical.CreateOperation {
 		role.TTL = time.Duration(d.Get("ttl").(int)) * time.Second
 	}
 
-	entry, err := logical.StorageEntryJSON("role/"+roleName, role)
+	if dockerRegistry, ok := d.GetOk("docker_registry"); ok {
+		role.DockerRegistry = dockerRegistry.(string)
+	}
+
+	if repositories, ok := d.GetOk("repositories"); ok {
+		role.Repositories = repositories.(map[string]string)
+	}
+	for format := range role.Repositories {
+		if !isPackageFormat(format) {
+			return logical.ErrorResponse(fmt.Sprintf("repositories contains unsupported format %q", format)), nil
+		}
+	}
+
+	if outputTemplate, ok := d.GetOk("output_template"); ok {
+		role.OutputTemplate = outputTemplate.(string)
+	}
+	if role.OutputTemplate != "" {
+		if err := validateOutputTemplate(role.OutputTemplate); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid output_template: %v", err)), nil
+		}
+	}
+
+	if includeReferenceToken, ok := d.GetOk("include_reference_token"); ok {
+		role.IncludeReferenceToken = includeReferenceToken.(bool)
+	}
+	if descriptionTemplate, ok := d.GetOk("description_template"); ok {
+		role.DescriptionTemplate = descriptionTemplate.(string)
+	}
+	if role.DescriptionTemplate != "" {
+		if err := validateDescriptionTemplate(role.DescriptionTemplate); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid description_template: %v", err)), nil
+		}
+	}
+
+	previousProjectKey := role.ProjectKey
+	if projectKey, ok := d.GetOk("project_key"); ok {
+		role.ProjectKey = projectKey.(string)
+	}
+	conf, err := b.readConfig(ctx, s)
 	if err != nil {
 		return nil, err
 	}
-	if err := req.Storage.Put(ctx, entry); err != nil {
+	if conf != nil && conf.ProjectKey != "" {
+		if role.ProjectKey == "" {
+			role.ProjectKey = conf.ProjectKey
+		}
+		if role.ProjectKey != conf.ProjectKey {
+			return logical.ErrorResponse(fmt.Sprintf("project_key must be %q, the project of the configured credentials", conf.ProjectKey)), nil
+		}
+		// Managing users, groups and permission targets requires global admin
+		if role.UserMode == userModeDynamic || len(role.PermissionRepositories) > 0 || role.GroupTemplate != "" {
+			return logical.ErrorResponse("dynamic users, groups and permission targets cannot be used with project credentials"), nil
+		}
+	}
+	// Like groups, the project is only checked when it changes
+	projectChanged := op == logical.CreateOperation || previousProjectKey != role.ProjectKey
+	if role.ProjectKey != "" && projectChanged {
+		if resp, err := b.validateRoleProject(ctx, s, role); resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	constraints, err := b.readConstraintsConfig(ctx, s)
+	if err != nil {
 		return nil, err
 	}
+	if err := constraints.checkRole(role); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("role violates constraints: %v", err)), nil
+	}
+	if resp, err := b.validateRolePolicy(ctx, s, role); resp != nil || err != nil {
+		return resp, err
+	}
+	// Groups deleted after the role was written must not block unrelated updates
+	groupsChanged := op == logical.CreateOperation || !strutil.EquivalentSlices(previousGroups, role.MemberOfGroups)
+	if groupsChanged && !d.Get("skip_validation").(bool) {
+		if resp, err := b.validateRoleGroups(ctx, s, role, known); resp != nil || err != nil {
+			return resp, err
+		}
+	}
 
 	return nil, nil
 }
 
 func (b *backend) pathRolesDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	name := d.Get("name").(string)
-	if err := req.Storage.Delete(ctx, "role/"+name); err != nil {
+
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := readRole(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	if err := b.recordRoleVersion(ctx, req.Storage, name, nil, req.EntityID, roleChangeDelete); err != nil {
 		return nil, err
 	}
 	return nil, nil
 }
 
+const (
+	// Tokens are issued to a transient user which Artifactory creates from
+	// the username and groups in the token scope.
+	userModeTransient = "transient"
+	// A user with the role's groups is created for each lease and deleted
+	// when the lease is revoked.
+	userModeDynamic = "dynamic"
+)
+
 type roleConfig struct {
-	Username       string        `json:"username"`
-	MemberOfGroups []string      `json:"member_of_groups"`
-	TTL            time.Duration `json:"lease"`
+	// Version of the stored representation, see upgradeRole
+	Version int `json:"version"`
+	// Version of the role in its history, see recordRoleVersion
+	RoleVersion int `json:"role_version"`
+
+	Description    string            `json:"description"`
+	Tags           []string          `json:"tags"`
+	Metadata       map[string]string `json:"metadata"`
+	Username       string            `json:"username"`
+	UserMode       string            `json:"user_mode"`
+	MemberOfGroups []string          `json:"member_of_groups"`
+	AllowAllGroups bool              `json:"allow_all_groups"`
+	TTL            time.Duration     `json:"ttl"`
+	DockerRegistry string            `json:"docker_registry"`
+	Repositories   map[string]string `json:"repositories"`
+	OutputTemplate string            `json:"output_template"`
+
+	// Permission target created for each lease
+	PermissionRepositories    []string `json:"permission_repositories"`
+	PermissionActions         []string `json:"permission_actions"`
+	PermissionIncludePatterns []string `json:"permission_include_patterns"`
+	PermissionExcludePatterns []string `json:"permission_exclude_patterns"`
+
+	// Template of the group created for each lease
+	GroupTemplate string `json:"group_template"`
+
+	// JFrog project tokens are issued for
+	ProjectKey string `json:"project_key"`
+
+	// Access API token options
+	IncludeReferenceToken bool   `json:"include_reference_token"`
+	DescriptionTemplate   string `json:"description_template"`
 }
@@ -0,0 +1,92 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtGroupService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/group"
+)
+
+// Role name taken by the audit endpoint
+const roleAuditName = "audit"
+
+func pathRolesAudit(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + roleAuditName + "$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathRolesAuditRead,
+		},
+		HelpSynopsis:    pathRolesAuditHelpSyn,
+		HelpDescription: pathRolesAuditHelpDesc,
+	}
+}
+
+func (b *backend) pathRolesAuditRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	policy, err := b.readPolicyConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	auditor, err := b.newScopeAuditor(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := listRoleNames(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := map[string]interface{}{}
+	for _, name := range names {
+		role, err := readRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+
+		scope, err := auditor.audit(role)
+		switch {
+		case err == rtGroupService.ErrGroupsForbidden:
+			return logical.ErrorResponse("configured credentials cannot read groups to check roles for admin privileges"), nil
+		case err != nil:
+			return nil, fmt.Errorf("Failed to audit role %s: %v", name, err)
+		}
+		if !scope.AllGroups && scope.AdminReason == "" {
+			continue
+		}
+
+		violations := policy.violations(role, scope)
+		if violations == nil {
+			violations = []string{}
+		}
+		roles[name] = map[string]interface{}{
+			"all_groups":   scope.AllGroups,
+			"admin":        scope.AdminReason != "",
+			"admin_reason": scope.AdminReason,
+			"violations":   violations,
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"roles":           roles,
+			"admin_unchecked": auditor == nil,
+		},
+	}, nil
+}
+
+const pathRolesAuditHelpSyn = `
+Report roles whose tokens carry broad or admin scopes.
+`
+
+const pathRolesAuditHelpDesc = `
+Lists roles whose tokens are scoped to all groups of the user, or carry admin
+privileges through their groups or user, along with the ways they violate
+the policy set in config/policy. Admin privileges cannot be checked before
+the engine is configured.
+`
@@ -0,0 +1,53 @@
+package artifactory
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathRolesExport(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles-export$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathRolesExportRead,
+		},
+		HelpSynopsis:    pathRolesExportHelpSyn,
+		HelpDescription: pathRolesExportHelpDesc,
+	}
+}
+
+func (b *backend) pathRolesExportRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	names, err := listRoleNames(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := map[string]interface{}{}
+	for _, name := range names {
+		role, err := readRole(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		roles[name] = roleResponseData(role)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"roles": roles,
+		},
+	}, nil
+}
+
+const pathRolesExportHelpSyn = `
+Export the definitions of every role.
+`
+
+const pathRolesExportHelpDesc = `
+Returns every role, including those within namespaces, keyed by name. The
+response data can be written to roles-import as is.
+`
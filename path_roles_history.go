@@ -0,0 +1,170 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Last segments of the paths of the history endpoints, which roles cannot
+// be named with
+const (
+	roleHistoryName  = "history"
+	roleRollbackName = "rollback"
+)
+
+func pathRoleHistory(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + roleNameRegex("name") + "/" + roleHistoryName + "$",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathRoleHistoryRead,
+		},
+		HelpSynopsis:    pathRoleHistoryHelpSyn,
+		HelpDescription: pathRoleHistoryHelpDesc,
+	}
+}
+
+func pathRoleRollback(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + roleNameRegex("name") + "/" + roleRollbackName + "$",
+		Fields: map[string]*framework.FieldSchema{
+			"name": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Name of the role",
+			},
+
+			"version": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Description: "Version of the role in its history to restore.",
+			},
+
+			"skip_validation": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Restore the role without checking that member_of_groups exist in Artifactory.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRoleRollbackWrite,
+		},
+		HelpSynopsis:    pathRoleRollbackHelpSyn,
+		HelpDescription: pathRoleRollbackHelpDesc,
+	}
+}
+
+func (b *backend) pathRoleHistoryRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	history, err := readRoleHistory(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, nil
+	}
+
+	versions := make([]interface{}, 0, len(history))
+	for _, version := range history {
+		var role map[string]interface{}
+		if version.Role != nil {
+			role = roleResponseData(version.Role)
+		}
+		versions = append(versions, map[string]interface{}{
+			"version":   version.Version,
+			"time":      version.Time.Format(time.RFC3339),
+			"entity_id": version.EntityID,
+			"operation": version.Operation,
+			"role":      role,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"versions": versions,
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleRollbackWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	if reason := reservedRoleName(name); reason != "" {
+		return logical.ErrorResponse(reason), nil
+	}
+
+	target := d.Get("version").(int)
+	if target <= 0 {
+		return logical.ErrorResponse("version must be a positive number"), nil
+	}
+
+	lock := b.roleLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	history, err := readRoleHistory(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	var version *roleVersion
+	for _, v := range history {
+		if v.Version == target {
+			version = v
+			break
+		}
+	}
+	if version == nil {
+		return logical.ErrorResponse(fmt.Sprintf("version %d of role %q is not in its history", target, name)), nil
+	}
+	if version.Role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("version %d of role %q deleted the role", target, name)), nil
+	}
+
+	// The version is written again as the next version, so it is validated
+	// against the current config as any other write would be
+	data := roleResponseData(version.Role)
+	data["skip_validation"] = d.Get("skip_validation").(bool)
+	role, reason, err := b.buildRole(ctx, req.Storage, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to validate version %d of role %s: %v", target, name, err)
+	}
+	if reason != "" {
+		return logical.ErrorResponse(fmt.Sprintf("version %d of role %q is no longer valid: %s", target, name, reason)), nil
+	}
+
+	if err := b.recordRoleVersion(ctx, req.Storage, name, role, req.EntityID, roleChangeRollback); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"role_version": role.RoleVersion,
+		},
+	}, nil
+}
+
+const pathRoleHistoryHelpSyn = `
+Read the change history of a role.
+`
+
+const pathRoleHistoryHelpDesc = `
+Returns the last versions of a role, oldest first, with the time of each
+change, the entity which made it and the role as it was written. The history
+is kept after the role is deleted, so that it can be rolled back.
+`
+
+const pathRoleRollbackHelpSyn = `
+Restore a previous version of a role.
+`
+
+const pathRoleRollbackHelpDesc = `
+Writes a version from the history of a role as its next version. The version
+is validated as any other write, so is rejected if it no longer satisfies the
+policy or constraints of the engine.
+`
@@ -0,0 +1,258 @@
+package artifactory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func writeRoleAs(t *testing.T, b logical.Backend, storage logical.Storage, entityID string, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   storage,
+		EntityID:  entityID,
+		Data:      data,
+	})
+}
+
+func readRoleHistoryVersions(t *testing.T, b logical.Backend, storage logical.Storage, name string) []interface{} {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/" + name + "/history",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if resp == nil {
+		t.Fatalf("Expected history of role %s\n", name)
+	}
+	return resp.Data["versions"].([]interface{})
+}
+
+func TestRole_History(t *testing.T) {
+	b, storage := newBackend(t)
+
+	resp, err := writeRoleAs(t, b, storage, "alice", logical.CreateOperation, "roles/teamA/reader", map[string]interface{}{
+		"member_of_groups": "readers",
+		"ttl":              600,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	resp, err = writeRoleAs(t, b, storage, "bob", logical.UpdateOperation, "roles/teamA/reader", map[string]interface{}{
+		"ttl": 1200,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/teamA/reader",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if resp.Data["role_version"] != 2 {
+		t.Fatalf("Expected role version 2, got: %v\n", resp.Data["role_version"])
+	}
+
+	versions := readRoleHistoryVersions(t, b, storage, "teamA/reader")
+	if len(versions) != 2 {
+		t.Fatalf("Expected 2 versions, got: %#v\n", versions)
+	}
+	first := versions[0].(map[string]interface{})
+	second := versions[1].(map[string]interface{})
+	if first["version"] != 1 || first["entity_id"] != "alice" || first["operation"] != roleChangeCreate || first["role"].(map[string]interface{})["ttl"] != int64(600) {
+		t.Fatalf("Unexpected first version: %#v\n", first)
+	}
+	if second["version"] != 2 || second["entity_id"] != "bob" || second["operation"] != roleChangeUpdate {
+		t.Fatalf("Unexpected second version: %#v\n", second)
+	}
+
+	// Rolling back writes the version again as the next version
+	resp, err = writeRoleAs(t, b, storage, "carol", logical.UpdateOperation, "roles/teamA/reader/rollback", map[string]interface{}{
+		"version": 1,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if resp.Data["role_version"] != 3 {
+		t.Fatalf("Expected rollback to write version 3, got: %v\n", resp.Data)
+	}
+	role, err := readRole(context.Background(), storage, "teamA/reader")
+	if err != nil || role == nil || role.TTL.Seconds() != 600 || role.RoleVersion != 3 {
+		t.Fatalf("Expected role to be rolled back, got: %#v err:%v\n", role, err)
+	}
+
+	for _, version := range []int{0, 99} {
+		resp, err = writeRoleAs(t, b, storage, "carol", logical.UpdateOperation, "roles/teamA/reader/rollback", map[string]interface{}{
+			"version": version,
+		})
+		assertLogicalResponse(t, FailWithLogicalError, err, resp)
+	}
+
+	// History is kept when the role is deleted, so it can be restored
+	resp, err = writeRoleAs(t, b, storage, "dave", logical.DeleteOperation, "roles/teamA/reader", nil)
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	versions = readRoleHistoryVersions(t, b, storage, "teamA/reader")
+	deleted := versions[len(versions)-1].(map[string]interface{})
+	if deleted["version"] != 4 || deleted["operation"] != roleChangeDelete || deleted["role"].(map[string]interface{}) != nil {
+		t.Fatalf("Expected deletion to be recorded, got: %#v\n", deleted)
+	}
+
+	resp, err = writeRoleAs(t, b, storage, "dave", logical.UpdateOperation, "roles/teamA/reader/rollback", map[string]interface{}{
+		"version": 4,
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+	resp, err = writeRoleAs(t, b, storage, "dave", logical.UpdateOperation, "roles/teamA/reader/rollback", map[string]interface{}{
+		"version": 2,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	role, err = readRole(context.Background(), storage, "teamA/reader")
+	if err != nil || role == nil || role.TTL.Seconds() != 1200 || role.RoleVersion != 5 {
+		t.Fatalf("Expected deleted role to be restored, got: %#v err:%v\n", role, err)
+	}
+
+	// Only the last versions are kept
+	for i := 0; i < defaultRoleHistoryVersions; i++ {
+		resp, err = writeRoleAs(t, b, storage, "erin", logical.UpdateOperation, "roles/teamA/reader", map[string]interface{}{
+			"ttl": 1200 + i,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+	versions = readRoleHistoryVersions(t, b, storage, "teamA/reader")
+	if len(versions) != defaultRoleHistoryVersions || versions[0].(map[string]interface{})["version"] != 6 {
+		t.Fatalf("Expected the last %d versions, got: %#v\n", defaultRoleHistoryVersions, versions)
+	}
+
+	// Rolled back versions must satisfy the current constraints
+	resp, err = writeRoleAs(t, b, storage, "", logical.UpdateOperation, "config/constraints", map[string]interface{}{
+		"max_ttl": "1000s",
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	resp, err = writeRoleAs(t, b, storage, "", logical.UpdateOperation, "roles/teamA/reader/rollback", map[string]interface{}{
+		"version": 6,
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+
+	// Roles cannot take the names of the history endpoints
+	resp, err = writeRoleAs(t, b, storage, "", logical.CreateOperation, "roles/history", map[string]interface{}{
+		"member_of_groups": "readers",
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/unknown/history",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if resp != nil {
+		t.Fatalf("Expected no history for unknown role, got: %#v\n", resp)
+	}
+}
+
+// slowStorage delays reading role history, so that concurrent writes to a
+// role overlap.
+type slowStorage struct {
+	logical.Storage
+}
+
+func (s *slowStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	if strings.HasPrefix(key, "role-history/") {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func TestRole_HistoryVersions(t *testing.T) {
+	b, inmem := newBackend(t)
+	storage := &slowStorage{inmem}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/housekeeping",
+		Storage:   storage,
+		Data:      map[string]interface{}{"role_history_versions": 50},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = writeRoleAs(t, b, storage, "alice", logical.CreateOperation, "roles/test", map[string]interface{}{
+		"member_of_groups": "readers",
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// Concurrent writes to a role each record their own version
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writeRoleAs(t, b, storage, "bob", logical.UpdateOperation, "roles/test", map[string]interface{}{
+				"ttl": 600 + i,
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	versions := readRoleHistoryVersions(t, b, storage, "test")
+	if len(versions) != 21 {
+		t.Fatalf("Expected 21 versions, got: %d\n", len(versions))
+	}
+	for i, version := range versions {
+		if version.(map[string]interface{})["version"] != i+1 {
+			t.Fatalf("Expected consecutive versions, got: %#v\n", versions)
+		}
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/housekeeping",
+		Storage:   storage,
+		Data:      map[string]interface{}{"role_history_versions": 2},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = writeRoleAs(t, b, storage, "alice", logical.UpdateOperation, "roles/test", map[string]interface{}{
+		"ttl": 1200,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	versions = readRoleHistoryVersions(t, b, storage, "test")
+	if len(versions) != 2 || versions[1].(map[string]interface{})["version"] != 22 {
+		t.Fatalf("Expected the last 2 versions, got: %#v\n", versions)
+	}
+}
+
+func TestRole_HistoryConcurrentCreate(t *testing.T) {
+	b, inmem := newBackend(t)
+	storage := &slowStorage{inmem}
+
+	// Creates which raced past the existence check update the role written
+	// by the first
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writeRoleAs(t, b, storage, "bob", logical.CreateOperation, "roles/test", map[string]interface{}{
+				"member_of_groups": "readers",
+				"ttl":              600 + i,
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	versions := readRoleHistoryVersions(t, b, storage, "test")
+	if len(versions) != 5 {
+		t.Fatalf("Expected 5 versions, got: %d\n", len(versions))
+	}
+	for i, version := range versions {
+		expected := roleChangeUpdate
+		if i == 0 {
+			expected = roleChangeCreate
+		}
+		if operation := version.(map[string]interface{})["operation"]; operation != expected {
+			t.Fatalf("Expected version %d to be a %s, got: %v\n", i+1, expected, operation)
+		}
+	}
+}
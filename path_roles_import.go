@@ -0,0 +1,353 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var roleNameRe = regexp.MustCompile("^" + roleNameRegex("name") + "$")
+
+func pathRolesImport(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles-import/?(?P<prefix>(" + roleNameSegmentRegex + "/)*)$",
+		Fields: map[string]*framework.FieldSchema{
+			"prefix": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Namespace to import the roles into, e.g. teamA/. Role names are relative to it.",
+			},
+
+			"roles": &framework.FieldSchema{
+				Type:        framework.TypeMap,
+				Description: "Role definitions keyed by name, with the fields of roles/:name.",
+			},
+
+			"document": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "A YAML or JSON document with the roles to import under roles, as returned by roles-export. Cannot be combined with roles.",
+			},
+
+			"dry_run": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Validate the roles and report the changes without writing them.",
+			},
+
+			"skip_validation": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: "Import the roles without checking that their member_of_groups exist in Artifactory.",
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRolesImportWrite,
+		},
+		HelpSynopsis:    pathRolesImportHelpSyn,
+		HelpDescription: pathRolesImportHelpDesc,
+	}
+}
+
+// importedRole is a role definition to be written by an import.
+type importedRole struct {
+	name     string
+	role     *roleConfig
+	existing *roleConfig
+
+	// History of the role before it was written
+	history []*roleVersion
+}
+
+func (b *backend) pathRolesImportWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	definitions := d.Get("roles").(map[string]interface{})
+	if document := d.Get("document").(string); document != "" {
+		if len(definitions) > 0 {
+			return logical.ErrorResponse("roles and document cannot both be set"), nil
+		}
+		var err error
+		if definitions, err = parseRolesDocument(document); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid document: %v", err)), nil
+		}
+	}
+	if len(definitions) == 0 {
+		return logical.ErrorResponse("roles cannot be empty"), nil
+	}
+	skipValidation := d.Get("skip_validation").(bool)
+
+	// Names are relative to the namespace of the path, so that access to
+	// roles-import/<namespace>/ only grants writes to roles within it
+	prefix := d.Get("prefix").(string)
+	named := make(map[string]interface{}, len(definitions))
+	names := make([]string, 0, len(definitions))
+	for name, definition := range definitions {
+		named[prefix+name] = definition
+		names = append(names, prefix+name)
+	}
+	sort.Strings(names)
+
+	// Roles are validated against their existing definition, so are locked
+	// until they are written
+	b.roleImportLock.Lock()
+	defer b.roleImportLock.Unlock()
+	for _, lock := range locksutil.LocksForKeys(b.roleLocks, names) {
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	// Every role is validated before any is written, so that an invalid
+	// document changes nothing. Groups are listed once for all roles.
+	known := &knownGroups{}
+	var imports []*importedRole
+	var invalid []string
+	for _, name := range names {
+		imported, reason, err := b.importRole(ctx, req.Storage, name, named[name], skipValidation, known)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to validate role %s: %v", name, err)
+		}
+		if reason != "" {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", name, reason))
+			continue
+		}
+		imports = append(imports, imported)
+	}
+	if len(invalid) > 0 {
+		return logical.ErrorResponse(fmt.Sprintf("invalid roles:\n%s", strings.Join(invalid, "\n"))), nil
+	}
+
+	created := []string{}
+	updated := []string{}
+	unchanged := []string{}
+	changes := map[string]interface{}{}
+	var writes []*importedRole
+	for _, imported := range imports {
+		switch {
+		case imported.existing == nil:
+			created = append(created, imported.name)
+		default:
+			diff := roleChanges(imported.existing, imported.role)
+			if len(diff) == 0 {
+				unchanged = append(unchanged, imported.name)
+				continue
+			}
+			updated = append(updated, imported.name)
+			changes[imported.name] = diff
+		}
+		writes = append(writes, imported)
+	}
+
+	dryRun := d.Get("dry_run").(bool)
+	if !dryRun {
+		if err := b.writeImportedRoles(ctx, req.Storage, writes, req.EntityID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"created":   created,
+			"updated":   updated,
+			"unchanged": unchanged,
+			"changes":   changes,
+			"dry_run":   dryRun,
+		},
+	}, nil
+}
+
+// importRole builds the role defined by definition, replacing any existing
+// role of the same name. A reason is returned if the definition is invalid.
+func (b *backend) importRole(ctx context.Context, s logical.Storage, name string, definition interface{}, skipValidation bool, known *knownGroups) (*importedRole, string, error) {
+	if !roleNameRe.MatchString(name) {
+		return nil, "invalid role name", nil
+	}
+	if reason := reservedRoleName(name); reason != "" {
+		return nil, reason, nil
+	}
+
+	raw, ok := definition.(map[string]interface{})
+	if !ok {
+		return nil, "role definition must be an object", nil
+	}
+	data := make(map[string]interface{}, len(raw)+1)
+	for k, v := range raw {
+		data[k] = v
+	}
+	if skipValidation {
+		data["skip_validation"] = true
+	}
+
+	role, reason, err := b.buildRole(ctx, s, data, known)
+	if err != nil || reason != "" {
+		return nil, reason, err
+	}
+
+	existing, err := readRole(ctx, s, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &importedRole{
+		name:     name,
+		role:     role,
+		existing: existing,
+	}, "", nil
+}
+
+// parseRolesDocument returns the roles of a YAML or JSON document, keyed by
+// name. JSON documents are valid YAML.
+func parseRolesDocument(document string) (map[string]interface{}, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(document), &raw); err != nil {
+		return nil, err
+	}
+	parsed, err := normalizeYAML(raw)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := parsed.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("document must be a map")
+	}
+	for k := range doc {
+		if k != "roles" {
+			return nil, fmt.Errorf("unknown field %q", k)
+		}
+	}
+	if doc["roles"] == nil {
+		return nil, nil
+	}
+	roles, ok := doc["roles"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("roles must be a map")
+	}
+	return roles, nil
+}
+
+// normalizeYAML converts the maps decoded from YAML, which may have keys of
+// any type, to the maps with string keys that role fields are parsed from.
+func normalizeYAML(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v is not a string", k)
+			}
+			normalized, err := normalizeYAML(value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = normalized
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, value := range v {
+			normalized, err := normalizeYAML(value)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = normalized
+		}
+		return l, nil
+	default:
+		return v, nil
+	}
+}
+
+// writeImportedRoles writes every role, restoring the roles already written
+// and their history if any write fails. The caller must hold the lock of
+// every role.
+func (b *backend) writeImportedRoles(ctx context.Context, s logical.Storage, imports []*importedRole, entityID string) error {
+	for i, imported := range imports {
+		history, err := readRoleHistory(ctx, s, imported.name)
+		if err != nil {
+			return restoreImportedRoles(ctx, s, imports[:i], imported.name, err)
+		}
+		imported.history = history
+
+		if err := b.recordRoleVersion(ctx, s, imported.name, imported.role, entityID, roleChangeImport); err != nil {
+			return restoreImportedRoles(ctx, s, imports[:i+1], imported.name, err)
+		}
+	}
+	return nil
+}
+
+// restoreImportedRoles undoes the writes of an import which failed to write
+// the named role.
+func restoreImportedRoles(ctx context.Context, s logical.Storage, written []*importedRole, name string, err error) error {
+	for _, imported := range written {
+		if restoreErr := restoreImportedRole(ctx, s, imported); restoreErr != nil {
+			return fmt.Errorf("Failed to import role %s: %v, and failed to restore role %s: %v", name, err, imported.name, restoreErr)
+		}
+	}
+	return fmt.Errorf("Failed to import role %s: %v", name, err)
+}
+
+func restoreImportedRole(ctx context.Context, s logical.Storage, imported *importedRole) error {
+	if imported.existing == nil {
+		if err := s.Delete(ctx, "role/"+imported.name); err != nil {
+			return err
+		}
+	} else if err := writeRole(ctx, s, imported.name, imported.existing); err != nil {
+		return err
+	}
+	return writeRoleHistory(ctx, s, imported.name, imported.history)
+}
+
+// roleChanges returns the old and new values of each field which differs
+// between the roles.
+func roleChanges(existing, role *roleConfig) map[string]interface{} {
+	before := roleResponseData(existing)
+	after := roleResponseData(role)
+
+	changes := map[string]interface{}{}
+	for field, value := range after {
+		if roleFieldEqual(before[field], value) {
+			continue
+		}
+		changes[field] = map[string]interface{}{
+			"old": before[field],
+			"new": value,
+		}
+	}
+	return changes
+}
+
+// roleFieldEqual compares field values, treating nil and empty lists and
+// maps as equal since they are stored interchangeably.
+func roleFieldEqual(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == vb.Kind() && (va.Kind() == reflect.Slice || va.Kind() == reflect.Map) && va.Len() == 0 && vb.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+const pathRolesImportHelpSyn = `
+Create or replace many roles at once.
+`
+
+const pathRolesImportHelpDesc = `
+Writes each role in roles, keyed by name, with the fields of roles/:name.
+Imported roles replace any existing role of the same name, fields which are
+not given take their defaults. Every role is validated before any is written,
+so an invalid role fails the whole import, and roles already written are
+restored if a later write fails. Roles not in the import are left unchanged.
+
+Roles may instead be given as a YAML or JSON document in document, with the
+roles under roles. Groups are listed from Artifactory once per import.
+
+The response lists the roles created, updated and unchanged, with the old and
+new values of each changed field. Set dry_run to report the changes without
+writing them. The output of roles-export can be imported as is.
+
+Writing to roles-import can create or replace a role in any namespace. To
+delegate imports for a namespace, grant roles-import/<namespace>/ instead,
+which imports the roles with names relative to that namespace.
+`
@@ -0,0 +1,296 @@
+package artifactory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func importRoles(t *testing.T, b logical.Backend, storage logical.Storage, data map[string]interface{}) (*logical.Response, error) {
+	t.Helper()
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles-import",
+		Storage:   storage,
+		Data:      data,
+	})
+}
+
+func assertImported(t *testing.T, resp *logical.Response, field string, expected ...string) {
+	t.Helper()
+	names := resp.Data[field].([]string)
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %s %v, got: %v\n", field, expected, names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Fatalf("Expected %s %v, got: %v\n", field, expected, names)
+		}
+	}
+}
+
+func TestRoles_Import(t *testing.T) {
+	b, storage := newBackend(t)
+
+	roles := map[string]interface{}{
+		"reader": map[string]interface{}{
+			"member_of_groups": []interface{}{"readers"},
+			"ttl":              600,
+		},
+		"teamA/ci/deployer": map[string]interface{}{
+			"member_of_groups": "deployers",
+			"tags":             []interface{}{"ci"},
+		},
+	}
+
+	// A dry run reports the changes without writing them
+	resp, err := importRoles(t, b, storage, map[string]interface{}{"roles": roles, "dry_run": true})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "created", "reader", "teamA/ci/deployer")
+	if role, _ := readRole(context.Background(), storage, "reader"); role != nil {
+		t.Fatalf("Expected dry run not to write roles, got: %#v\n", role)
+	}
+
+	resp, err = importRoles(t, b, storage, map[string]interface{}{"roles": roles})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "created", "reader", "teamA/ci/deployer")
+	role, err := readRole(context.Background(), storage, "teamA/ci/deployer")
+	if err != nil || role == nil || role.MemberOfGroups[0] != "deployers" {
+		t.Fatalf("Expected role to be imported, got: %#v err:%v\n", role, err)
+	}
+	// Defaults are applied as on create
+	if role.UserMode != userModeTransient || role.PermissionActions[0] != "read" {
+		t.Fatalf("Expected imported role to have defaults, got: %#v\n", role)
+	}
+
+	// Importing again reports the changed fields
+	roles["reader"] = map[string]interface{}{
+		"member_of_groups": []interface{}{"readers"},
+		"ttl":              "1h",
+	}
+	resp, err = importRoles(t, b, storage, map[string]interface{}{"roles": roles})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "updated", "reader")
+	assertImported(t, resp, "unchanged", "teamA/ci/deployer")
+	change := resp.Data["changes"].(map[string]interface{})["reader"].(map[string]interface{})
+	if len(change) != 1 || change["ttl"].(map[string]interface{})["new"] != int64(3600) {
+		t.Fatalf("Expected ttl change to be reported, got: %#v\n", change)
+	}
+
+	// An invalid role fails the whole import
+	invalid := []map[string]interface{}{
+		{"writer": map[string]interface{}{"member_of_groups": "writers"}, "reader": map[string]interface{}{}},
+		{"writer": map[string]interface{}{"member_of_groups": "writers", "member_of_group": "writers"}},
+		{"writer": "writers"},
+		{"audit": map[string]interface{}{"member_of_groups": "writers"}},
+		{"teamA/rollback": map[string]interface{}{"member_of_groups": "writers"}},
+		{"teamA//writer": map[string]interface{}{"member_of_groups": "writers"}},
+		{"writer": map[string]interface{}{"member_of_groups": "writers", "ttl": "forever"}},
+	}
+	for _, roles := range invalid {
+		resp, err = importRoles(t, b, storage, map[string]interface{}{"roles": roles})
+		assertLogicalResponse(t, FailWithLogicalError, err, resp)
+		if role, _ := readRole(context.Background(), storage, "writer"); role != nil {
+			t.Fatalf("Expected failed import not to write roles, got: %#v\n", role)
+		}
+	}
+
+	resp, err = importRoles(t, b, storage, map[string]interface{}{})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+}
+
+func TestRoles_ImportNamespace(t *testing.T) {
+	b, storage := newBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles-import/teamA/",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"roles": map[string]interface{}{
+				"ci/deployer": map[string]interface{}{"member_of_groups": "deployers"},
+			},
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "created", "teamA/ci/deployer")
+
+	role, err := readRole(context.Background(), storage, "teamA/ci/deployer")
+	if err != nil || role == nil {
+		t.Fatalf("Expected role to be imported into the namespace, got: %#v err:%v\n", role, err)
+	}
+	if role, _ := readRole(context.Background(), storage, "ci/deployer"); role != nil {
+		t.Fatalf("Expected role not to be imported outside the namespace, got: %#v\n", role)
+	}
+}
+
+// failingStorage fails to write a single key.
+type failingStorage struct {
+	logical.Storage
+	key string
+}
+
+func (s *failingStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if entry.Key == s.key {
+		return errors.New("put failed")
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+func TestRoles_ImportDocument(t *testing.T) {
+	var groupRequests int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/security/groups/" {
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+		groupRequests++
+		w.Write([]byte(`[{"name": "readers"}, {"name": "deployers"}]`))
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	document := `
+roles:
+  reader:
+    member_of_groups: [readers]
+    ttl: 600
+    metadata:
+      team: platform
+  teamA/ci/deployer:
+    member_of_groups: deployers
+    tags:
+      - ci
+`
+	resp, err = importRoles(t, b, storage, map[string]interface{}{"document": document})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "created", "reader", "teamA/ci/deployer")
+	if groupRequests != 1 {
+		t.Fatalf("Expected groups to be listed once per import, got %d requests\n", groupRequests)
+	}
+
+	role, err := readRole(context.Background(), storage, "reader")
+	if err != nil || role == nil || role.TTL != 600*time.Second || role.Metadata["team"] != "platform" {
+		t.Fatalf("Expected role to be imported from YAML, got: %#v err:%v\n", role, err)
+	}
+
+	// JSON documents are YAML
+	resp, err = importRoles(t, b, storage, map[string]interface{}{
+		"document": `{"roles": {"reader": {"member_of_groups": ["readers"], "ttl": 600, "metadata": {"team": "platform"}}}}`,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "unchanged", "reader")
+
+	invalid := []map[string]interface{}{
+		{"document": "roles: [reader]"},
+		{"document": "reader:\n  member_of_groups: readers"},
+		{"document": "roles:\n  reader: {member_of_groups: readers"},
+		{"document": "roles:\n  1: {member_of_groups: readers}"},
+		{"document": "roles:\n  writer: {member_of_groups: writers}"},
+		{
+			"document": "roles:\n  writer: {member_of_groups: readers}",
+			"roles":    map[string]interface{}{"writer": map[string]interface{}{"member_of_groups": "readers"}},
+		},
+	}
+	for _, data := range invalid {
+		resp, err = importRoles(t, b, storage, data)
+		assertLogicalResponse(t, FailWithLogicalError, err, resp)
+		if role, _ := readRole(context.Background(), storage, "writer"); role != nil {
+			t.Fatalf("Expected failed import not to write roles, got: %#v\n", role)
+		}
+	}
+}
+
+func TestRoles_ImportRestoresOnFailure(t *testing.T) {
+	b, storage := newBackend(t)
+
+	resp, err := importRoles(t, b, storage, map[string]interface{}{
+		"roles": map[string]interface{}{
+			"a": map[string]interface{}{"member_of_groups": "before"},
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = importRoles(t, b, &failingStorage{Storage: storage, key: "role/c"}, map[string]interface{}{
+		"roles": map[string]interface{}{
+			"a": map[string]interface{}{"member_of_groups": "after"},
+			"b": map[string]interface{}{"member_of_groups": "after"},
+			"c": map[string]interface{}{"member_of_groups": "after"},
+		},
+	})
+	assertLogicalResponse(t, FailWithError, err, resp)
+
+	role, err := readRole(context.Background(), storage, "a")
+	if err != nil || role == nil || role.MemberOfGroups[0] != "before" {
+		t.Fatalf("Expected existing role to be restored, got: %#v err:%v\n", role, err)
+	}
+	if role, _ := readRole(context.Background(), storage, "b"); role != nil {
+		t.Fatalf("Expected created role to be removed, got: %#v\n", role)
+	}
+	history, err := readRoleHistory(context.Background(), storage, "a")
+	if err != nil || len(history) != 1 || history[0].Operation != roleChangeImport {
+		t.Fatalf("Expected role history to be restored, got: %#v err:%v\n", history, err)
+	}
+	if history, _ := readRoleHistory(context.Background(), storage, "b"); history != nil {
+		t.Fatalf("Expected created role history to be removed, got: %#v\n", history)
+	}
+}
+
+func TestRoles_Export(t *testing.T) {
+	b, storage := newBackend(t)
+
+	for name, data := range map[string]map[string]interface{}{
+		"reader":          {"member_of_groups": "readers", "ttl": 600, "metadata": []string{"team=platform"}},
+		"teamA/ci/reader": {"username": "ci", "allow_all_groups": true},
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/" + name,
+			Storage:   storage,
+			Data:      data,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles-export",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	exported := resp.Data["roles"].(map[string]interface{})
+	if len(exported) != 2 || exported["teamA/ci/reader"].(map[string]interface{})["username"] != "ci" {
+		t.Fatalf("Expected every role to be exported, got: %#v\n", exported)
+	}
+
+	// The export can be imported as is
+	resp, err = importRoles(t, b, storage, map[string]interface{}{"roles": exported})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "unchanged", "reader", "teamA/ci/reader")
+
+	other, otherStorage := newBackend(t)
+	resp, err = importRoles(t, other, otherStorage, map[string]interface{}{"roles": exported})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	assertImported(t, resp, "created", "reader", "teamA/ci/reader")
+	role, err := readRole(context.Background(), otherStorage, "reader")
+	if err != nil || role == nil || role.Metadata["team"] != "platform" || role.TTL.Seconds() != 600 {
+		t.Fatalf("Expected exported role to be imported, got: %#v err:%v\n", role, err)
+	}
+}
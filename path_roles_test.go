@@ -36,6 +36,30 @@ func TestRole_Create(t *testing.T) {
 			map[string]interface{}{
 				"username":         "user",
 				"member_of_groups": "",
+				"allow_all_groups": true,
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-without-groups-not-allowed",
+			map[string]interface{}{
+				"username":         "user",
+				"member_of_groups": "",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-wildcard-group-not-allowed",
+			map[string]interface{}{
+				"username":         "user",
+				"member_of_groups": "*",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"audit",
+			map[string]interface{}{
+				"member_of_groups": "group",
 			},
 		},
 		{
@@ -51,6 +75,128 @@ func TestRole_Create(t *testing.T) {
 			"role-without-groups",
 			map[string]interface{}{},
 		},
+		{
+			ExpectedToSucceed,
+			"role-with-repositories",
+			map[string]interface{}{
+				"member_of_groups": "group",
+				"repositories":     "npm=npm-virtual,maven=maven-virtual",
+			},
+		},
+		{
+			ExpectedToSucceed,
+			"role-with-output-template",
+			map[string]interface{}{
+				"member_of_groups": "group",
+				"output_template":  "{{ .Username }}:{{ .AccessToken }}",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-invalid-output-template",
+			map[string]interface{}{
+				"member_of_groups": "group",
+				"output_template":  "{{ .Unknown }}",
+			},
+		},
+		{
+			ExpectedToSucceed,
+			"role-with-reference-token",
+			map[string]interface{}{
+				"member_of_groups":        "group",
+				"include_reference_token": true,
+				"description_template":    "vault {{ .RoleName }} for {{ .DisplayName }}",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-invalid-description-template",
+			map[string]interface{}{
+				"member_of_groups":     "group",
+				"description_template": "{{ .AccessToken }}",
+			},
+		},
+		{
+			ExpectedToSucceed,
+			"role-with-dynamic-user",
+			map[string]interface{}{
+				"member_of_groups": "group",
+				"user_mode":        "dynamic",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-dynamic-user-and-username",
+			map[string]interface{}{
+				"username":         "user",
+				"member_of_groups": "group",
+				"user_mode":        "dynamic",
+			},
+		},
+		{
+			ExpectedToSucceed,
+			"role-with-permission-target",
+			map[string]interface{}{
+				"user_mode":               "dynamic",
+				"permission_repositories": "libs-release-local,ANY REMOTE",
+				"permission_actions":      "read,deploy,delete,annotate",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-permission-target-and-transient-user",
+			map[string]interface{}{
+				"member_of_groups":        "group",
+				"permission_repositories": "libs-release-local",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-unsupported-permission-action",
+			map[string]interface{}{
+				"user_mode":               "dynamic",
+				"permission_repositories": "libs-release-local",
+				"permission_actions":      "admin",
+			},
+		},
+		{
+			ExpectedToSucceed,
+			"role-with-group-template",
+			map[string]interface{}{
+				"group_template": `{"description": "CI", "permission_targets": {"libs": ["read", "deploy"]}}`,
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-invalid-group-template",
+			map[string]interface{}{
+				"group_template": `{"permission_targets": {"libs": ["admin"]}}`,
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-group-template-and-username",
+			map[string]interface{}{
+				"username":       "user",
+				"group_template": `{"permission_targets": {"libs": ["read"]}}`,
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-unsupported-user-mode",
+			map[string]interface{}{
+				"member_of_groups": "group",
+				"user_mode":        "unknown",
+			},
+		},
+		{
+			FailWithLogicalError,
+			"role-with-unsupported-repository-format",
+			map[string]interface{}{
+				"member_of_groups": "group",
+				"repositories":     "docker=docker-virtual",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -71,7 +217,7 @@ func TestRole_Create(t *testing.T) {
 func TestRole_Create_UserScoped(t *testing.T) {
 	b, storage := newBackend(t)
 
-	roleData := map[string]interface{}{"username": "user"}
+	roleData := map[string]interface{}{"username": "user", "allow_all_groups": true}
 
 	req := &logical.Request{
 		Operation: logical.CreateOperation,
@@ -245,3 +391,228 @@ func TestRole_List(t *testing.T) {
 		t.Fatalf("Incorrect role count listed. Expected: %d Actual: %d\nresp=%v\n", expectedCount, actualCount, resp.Data)
 	}
 }
+
+func TestRole_Metadata(t *testing.T) {
+	b, storage := newBackend(t)
+
+	roles := map[string]map[string]interface{}{
+		"platform-ci": {
+			"member_of_groups": "group",
+			"description":      "Platform CI builds",
+			"tags":             "ci,platform",
+			"metadata":         []string{"team=platform", "ticket=OPS-123"},
+		},
+		"platform-deploy": {
+			"member_of_groups": "group",
+			"tags":             "platform",
+			"metadata":         "team=platform",
+		},
+		"web-ci": {
+			"member_of_groups": "group",
+			"tags":             "ci",
+			"metadata":         "team=web",
+		},
+	}
+	for name, data := range roles {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/" + name,
+			Storage:   storage,
+			Data:      data,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/platform-ci",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if resp.Data["description"] != "Platform CI builds" {
+		t.Fatalf("Expected description to be stored, got: %v\n", resp.Data)
+	}
+	if tags := resp.Data["tags"].([]string); len(tags) != 2 {
+		t.Fatalf("Expected tags to be stored, got: %v\n", tags)
+	}
+	if metadata := resp.Data["metadata"].(map[string]string); metadata["ticket"] != "OPS-123" {
+		t.Fatalf("Expected metadata to be stored, got: %v\n", metadata)
+	}
+
+	tests := []struct {
+		filter   map[string]interface{}
+		expected int
+	}{
+		{map[string]interface{}{}, 3},
+		{map[string]interface{}{"tag": "ci"}, 2},
+		{map[string]interface{}{"tag": "ci,platform"}, 1},
+		{map[string]interface{}{"metadata": "team=platform"}, 2},
+		{map[string]interface{}{"tag": "ci", "metadata": "team=web"}, 1},
+		{map[string]interface{}{"metadata": "team=unknown"}, 0},
+	}
+
+	for _, test := range tests {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "roles/",
+			Storage:   storage,
+			Data:      test.filter,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		keys, _ := resp.Data["keys"].([]string)
+		if len(keys) != test.expected {
+			t.Fatalf("Expected %d roles for filter %v, got: %v\n", test.expected, test.filter, keys)
+		}
+	}
+}
+
+func TestRole_ListWithInfo(t *testing.T) {
+	b, storage := newBackend(t)
+
+	roles := map[string]map[string]interface{}{
+		"a-reader": {"member_of_groups": "readers", "ttl": "1h", "tags": "ci"},
+		"b-writer": {"member_of_groups": "Readers,writers", "username": "ci-writer"},
+		"c-user":   {"username": "ci-user", "allow_all_groups": true},
+		"d-admin":  {"member_of_groups": "admins", "username": "admin"},
+	}
+	for name, data := range roles {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/" + name,
+			Storage:   storage,
+			Data:      data,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+
+	tests := []struct {
+		filter   map[string]interface{}
+		expected []string
+	}{
+		{map[string]interface{}{}, []string{"a-reader", "b-writer", "c-user", "d-admin"}},
+		{map[string]interface{}{"group": "readers"}, []string{"a-reader", "b-writer"}},
+		{map[string]interface{}{"group": "readers,writers"}, []string{"b-writer"}},
+		{map[string]interface{}{"username_prefix": "ci-"}, []string{"b-writer", "c-user"}},
+		{map[string]interface{}{"tag": "ci"}, []string{"a-reader"}},
+		{map[string]interface{}{"limit": 2}, []string{"a-reader", "b-writer"}},
+		{map[string]interface{}{"after": "b-writer", "limit": 1}, []string{"c-user"}},
+		{map[string]interface{}{"after": "b-writer", "username_prefix": "ci-"}, []string{"c-user"}},
+	}
+
+	for _, test := range tests {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "roles/",
+			Storage:   storage,
+			Data:      test.filter,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		keys, _ := resp.Data["keys"].([]string)
+		if len(keys) != len(test.expected) {
+			t.Fatalf("Expected roles %v for filter %v, got: %v\n", test.expected, test.filter, keys)
+		}
+		for i := range keys {
+			if keys[i] != test.expected[i] {
+				t.Fatalf("Expected roles %v for filter %v, got: %v\n", test.expected, test.filter, keys)
+			}
+		}
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "roles/",
+		Storage:   storage,
+		Data:      map[string]interface{}{"tag": "ci"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	info := resp.Data["key_info"].(map[string]interface{})["a-reader"].(map[string]interface{})
+	if info["ttl"].(int64) != 3600 || info["member_of_groups"].([]string)[0] != "readers" {
+		t.Fatalf("Unexpected key info: %v\n", info)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "roles/",
+		Storage:   storage,
+		Data:      map[string]interface{}{"limit": -1},
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+}
+
+func TestRole_Namespaces(t *testing.T) {
+	b, storage := newBackend(t)
+
+	for _, name := range []string{"reader", "teamA/ci/reader", "teamA/deploy", "teamB/reader"} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/" + name,
+			Storage:   storage,
+			Data:      map[string]interface{}{"member_of_groups": "group"},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	}
+
+	for _, name := range []string{"teamA//reader", "teamA/-reader", "/reader"} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/" + name,
+			Storage:   storage,
+			Data:      map[string]interface{}{"member_of_groups": "group"},
+		})
+		assertLogicalResponse(t, FailWithError, err, resp)
+	}
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"roles/", []string{"reader", "teamA/", "teamB/"}},
+		{"roles/teamA/", []string{"ci/", "deploy"}},
+		{"roles/teamA/ci/", []string{"reader"}},
+		{"roles/teamC/", nil},
+	}
+
+	for _, test := range tests {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      test.path,
+			Storage:   storage,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		keys, _ := resp.Data["keys"].([]string)
+		if len(keys) != len(test.expected) {
+			t.Fatalf("Expected %v listing %s, got: %v\n", test.expected, test.path, keys)
+		}
+		for i := range keys {
+			if keys[i] != test.expected[i] {
+				t.Fatalf("Expected %v listing %s, got: %v\n", test.expected, test.path, keys)
+			}
+		}
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/teamA/ci/reader",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if resp == nil || resp.Data["member_of_groups"].([]string)[0] != "group" {
+		t.Fatalf("Expected namespaced role to be read, got: %#v\n", resp)
+	}
+
+	names, err := listRoleNames(context.Background(), storage)
+	if err != nil {
+		t.Fatalf("Failed to list role names: %v\n", err)
+	}
+	if len(names) != 4 || names[1] != "teamA/ci/reader" {
+		t.Fatalf("Expected every role to be listed, got: %v\n", names)
+	}
+
+	if username := generateRoleUsername("teamA/ci/reader", "id"); username != "vault-teamA-ci-reader-id" {
+		t.Fatalf("Expected namespaces to be removed from generated username, got: %s\n", username)
+	}
+}
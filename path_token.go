@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 
@@ -15,12 +16,17 @@ import (
 
 func pathToken(b *backend) *framework.Path {
 	return &framework.Path{
-		Pattern: "token/" + framework.GenericNameRegex("name"),
+		Pattern: "token/" + roleNameRegex("name"),
 		Fields: map[string]*framework.FieldSchema{
 			"name": {
 				Type:        framework.TypeString,
 				Description: "The name of the role.",
 			},
+			"format": {
+				Type:        framework.TypeString,
+				Description: "Additionally render the access token in this format. Supported formats: docker, npm, pypi, maven, gradle, helm, nuget, go.",
+				Query:       true,
+			},
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation: b.pathTokenRead,
@@ -43,49 +49,242 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 		return logical.ErrorResponse("role does not exist"), nil
 	}
 
-	client, rtDetails, err := b.rtClient(ctx, req.Storage)
-	if client == nil || rtDetails == nil {
-		return nil, fmt.Errorf("Failed to create Artifactory client: %v\n", err)
+	format := d.Get("format").(string)
+	if err := validateTokenFormat(format, role); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	tokenService, err := b.tokenService(ctx, req.Storage)
+	if err != nil {
+		return nil, err
 	}
 
 	username := role.Username
 	if username == "" {
 		username = generateRoleUsername(roleName, req.ID)
 	}
-
-	tokenService := rtTokenService.NewAccessTokenService(client)
-	tokenService.SetArtifactoryDetails(rtDetails)
-	tokenResp, err := tokenService.CreateToken(&rtTokenService.CreateTokenRequest{
+	// Entities created for the lease share the generated username
+	resources := &leaseResources{
 		Username:    username,
-		Scope:       fmt.Sprintf("member-of-groups:%s", strings.Join(role.MemberOfGroups, ",")),
-		ExpiresIn:   int64(role.TTL.Seconds()),
-		Refreshable: false,
+		DynamicUser: role.UserMode == userModeDynamic,
+	}
+	if len(role.PermissionRepositories) > 0 {
+		resources.PermissionTarget = username
+	}
+	if role.GroupTemplate != "" {
+		tmpl, err := parseGroupTemplate(role.GroupTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group_template: %v", err)
+		}
+		resources.DynamicGroup = username
+		resources.GroupPermissionTargets = tmpl.leasePermissionTargets(username)
+	}
+
+	tokenReq := &rtTokenService.CreateTokenRequest{
+		Username:              username,
+		Scope:                 resources.scope(role),
+		ExpiresIn:             int64(role.TTL.Seconds()),
+		Refreshable:           false,
+		ProjectKey:            role.ProjectKey,
+		IncludeReferenceToken: role.IncludeReferenceToken,
+	}
+	if role.DescriptionTemplate != "" {
+		tokenReq.Description, err = renderDescriptionTemplate(role.DescriptionTemplate, &descriptionTemplateData{
+			RoleName:        roleName,
+			RoleDescription: role.Description,
+			Tags:            role.Tags,
+			Metadata:        role.Metadata,
+			Username:        username,
+			ProjectKey:      role.ProjectKey,
+			DisplayName:     req.DisplayName,
+			EntityID:        req.EntityID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to render description template: %v", err)
+		}
+	} else if tokenReq.AccessApi() {
+		// Only the Access API takes a description, which must not move
+		// tokens off the legacy API by itself
+		tokenReq.Description = defaultDescription(role)
+	}
+
+	// Constraints and policy may have been tightened since the role was written
+	constraints, err := b.readConstraintsConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if err := constraints.checkRole(role); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("role violates constraints: %v", err)), nil
+	}
+	if resp, err := b.validateRolePolicy(ctx, req.Storage, role); resp != nil || err != nil {
+		return resp, err
+	}
+
+	// Record the attempt so the token, and anything created for it, can be
+	// removed if it is created but never handed out.
+	walID, err := framework.PutWAL(ctx, req.Storage, walAccessTokenKind, &walAccessToken{
+		RoleName:         roleName,
+		Username:         username,
+		Transient:        role.Username == "",
+		DynamicUser:      resources.DynamicUser,
+		PermissionTarget: resources.PermissionTarget,
+		DynamicGroup:     resources.DynamicGroup,
+		ProjectKey:       role.ProjectKey,
+		AccessApi:        tokenReq.AccessApi(),
+
+		GroupPermissionTargets: resources.GroupPermissionTargets,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create access token: %v\n", err)
+		return nil, fmt.Errorf("Failed to write WAL entry: %v", err)
+	}
+
+	tokenResp, err := b.createLeaseToken(ctx, req.Storage, tokenService, resources, role, tokenReq)
+	if err != nil {
+		// The WAL entry is left to retry if this fails
+		if err := b.deleteLeaseResources(ctx, req.Storage, resources); err != nil {
+			b.Logger().Warn("failed to clean up after failed token creation", "username", username, "error", err)
+		}
+		return nil, err
+	}
+	b.Logger().Info("created access token", "role", roleName, "username", username, "token_id", tokenResp.TokenID)
+
+	issuanceID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	address := tokenService.GetArtifactoryDetails().GetUrl()
+	var expiresAt time.Time
+	if tokenResp.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+
+	data := map[string]interface{}{
+		"access_token": tokenResp.AccessToken,
+		"scope":        tokenResp.Scope,
+		"token_type":   tokenResp.TokenType,
+		"token_id":     tokenResp.TokenID,
+		"username":     username,
+		"expires_in":   tokenResp.ExpiresIn,
+		"expires_at":   nil,
+		"refreshable":  tokenResp.RefreshToken != "",
+		"audience":     tokenResp.Audience,
+		"address":      address,
+	}
+	if role.IncludeReferenceToken {
+		data["reference_token"] = tokenResp.ReferenceToken
+	}
+	// Tokens without an expiry remain valid until revoked
+	if !expiresAt.IsZero() {
+		data["expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	if formatter, ok := tokenFormatters[format]; ok {
+		formatted, err := formatter(&tokenFormatData{
+			Role:        role,
+			Username:    username,
+			AccessToken: tokenResp.AccessToken,
+			Address:     address,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to render %s format: %v", format, err)
+		}
+		for k, v := range formatted {
+			data[k] = v
+		}
+	}
+
+	if role.OutputTemplate != "" {
+		rendered, err := renderOutputTemplate(role.OutputTemplate, &outputTemplateData{
+			RoleName:    roleName,
+			Username:    username,
+			AccessToken: tokenResp.AccessToken,
+			Scope:       tokenResp.Scope,
+			ExpiresIn:   tokenResp.ExpiresIn,
+			ExpiresAt:   expiresAt,
+			Address:     address,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to render output template: %v", err)
+		}
+		data["rendered"] = rendered
 	}
 
 	resp := b.Secret(accessTokenSecretType).Response(
+		data,
 		map[string]interface{}{
-			"access_token": tokenResp.AccessToken,
-			"scope":        tokenResp.Scope,
-			"token_type":   tokenResp.TokenType,
-		},
-		map[string]interface{}{
-			"role_name": roleName,
-			"username":  username,
+			"role_name":                roleName,
+			"username":                 username,
+			"token_id":                 tokenResp.TokenID,
+			"issuance_id":              issuanceID,
+			"dynamic_user":             resources.DynamicUser,
+			"permission_target":        resources.PermissionTarget,
+			"dynamic_group":            resources.DynamicGroup,
+			"group_permission_targets": resources.GroupPermissionTargets,
+			"project_key":              role.ProjectKey,
+			"access_api":               tokenReq.AccessApi(),
+			"role_description":         role.Description,
+			"role_tags":                role.Tags,
+			"role_metadata":            role.Metadata,
+			"role_version":             role.RoleVersion,
 		},
 	)
 	resp.Secret.TTL = time.Duration(tokenResp.ExpiresIn) * time.Second
-	resp.Secret.MaxTTL = resp.Secret.MaxTTL
+
+	// Secrets are not renewable, so the lease ends at its TTL as capped by
+	// the mount.
+	leaseTTL := resp.Secret.TTL
+	if leaseTTL <= 0 {
+		leaseTTL = b.System().DefaultLeaseTTL()
+	}
+	if maxTTL := b.System().MaxLeaseTTL(); leaseTTL > maxTTL {
+		leaseTTL = maxTTL
+	}
+
+	err = putIssuanceRecord(ctx, req.Storage, issuanceID, &issuanceRecord{
+		RoleName:         roleName,
+		Username:         username,
+		Transient:        role.Username == "",
+		DynamicUser:      resources.DynamicUser,
+		PermissionTarget: resources.PermissionTarget,
+		DynamicGroup:     resources.DynamicGroup,
+		ProjectKey:       role.ProjectKey,
+		AccessApi:        tokenReq.AccessApi(),
+		ExpiresAt:        time.Now().Add(leaseTTL),
+
+		GroupPermissionTargets: resources.GroupPermissionTargets,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write issuance record: %v", err)
+	}
+
+	if err := framework.DeleteWAL(ctx, req.Storage, walID); err != nil {
+		return nil, fmt.Errorf("Failed to commit WAL entry: %v", err)
+	}
 
 	return resp, nil
 }
 
+// createLeaseToken creates the entities required by the role, if any, and
+// then the access token.
+func (b *backend) createLeaseToken(ctx context.Context, s logical.Storage, tokenService *rtTokenService.AccessTokenService, resources *leaseResources, role *roleConfig, tokenReq *rtTokenService.CreateTokenRequest) (*rtTokenService.CreateTokenResponse, error) {
+	if err := b.createLeaseResources(ctx, s, resources, role); err != nil {
+		return nil, err
+	}
+
+	tokenResp, err := tokenService.CreateToken(tokenReq)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create access token: %v\n", err)
+	}
+
+	return tokenResp, nil
+}
+
 // Generate a transient username that's highly unlikely to clash
-// with an existing Artifactory username.
+// with an existing Artifactory username. Usernames cannot contain the / of
+// namespaced role names.
 func generateRoleUsername(role, id string) string {
-	return fmt.Sprintf("vault-%s-%s", role, id)
+	return fmt.Sprintf("vault-%s-%s", strings.Replace(role, "/", "-", -1), id)
 }
 
 const pathTokenHelpSyn = `
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
 
@@ -31,7 +33,7 @@ func TestToken_Read(t *testing.T) {
 					&rtTokenService.CreateTokenResponse{
 						AccessToken: "abc123",
 						ExpiresIn:   3600,
-						Scope:       "api:* member-of-groups:readers",
+						Scope:       "api:* member-of-groups:group",
 						TokenType:   "Bearer",
 					})
 				if err != nil {
@@ -49,7 +51,7 @@ func TestToken_Read(t *testing.T) {
 					&rtTokenService.CreateTokenResponse{
 						AccessToken: "abc123",
 						ExpiresIn:   3600,
-						Scope:       "api:* member-of-groups:readers",
+						Scope:       "api:* member-of-groups:group",
 						TokenType:   "Bearer",
 					})
 				if err != nil {
@@ -69,9 +71,33 @@ func TestToken_Read(t *testing.T) {
 		{
 			FailWithLogicalError, // Backend has not been configured
 			false,
-			map[string]interface{}{"username": "user"},
+			map[string]interface{}{"username": "user", "allow_all_groups": true},
 			nil,
 		},
+		{
+			FailWithError, // Token grants more than the role
+			true,
+			map[string]interface{}{
+				"username":         "user",
+				"member_of_groups": "group",
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/security/token/revoke" {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				body, err := json.Marshal(
+					&rtTokenService.CreateTokenResponse{
+						AccessToken: "abc123",
+						Scope:       "api:* member-of-groups:group,admins",
+						TokenType:   "Bearer",
+					})
+				if err != nil {
+					t.Fatal("Encoding mock HTTP response failed!")
+				}
+				w.Write(body)
+			},
+		},
 		{
 			FailWithLogicalError, // HTTP 403 response from Artifactory
 			true,
@@ -111,6 +137,8 @@ func TestToken_Read(t *testing.T) {
 			assertLogicalResponse(t, ExpectedToSucceed, err, resp)
 		}
 		if test.role != nil {
+			// Groups are not served by the mock server
+			test.role["skip_validation"] = true
 			createRoleReq := &logical.Request{
 				Operation: logical.CreateOperation,
 				Path:      "roles/test",
@@ -132,3 +160,327 @@ func TestToken_Read(t *testing.T) {
 		}
 	}
 }
+
+func TestToken_ReadFormat(t *testing.T) {
+	tests := []struct {
+		expectation    Expectation
+		format         string
+		expectedFields []string
+	}{
+		{ExpectedToSucceed, "", []string{"access_token", "rendered"}},
+		{ExpectedToSucceed, "docker", []string{"access_token", "auths", "dockerconfigjson"}},
+		{ExpectedToSucceed, "npm", []string{"access_token", "npmrc"}},
+		{FailWithLogicalError, "pypi", nil}, // No repository configured
+		{FailWithLogicalError, "unknown", nil},
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+			AccessToken: "abc123",
+			ExpiresIn:   3600,
+			TokenType:   "Bearer",
+		})
+		if err != nil {
+			t.Fatal("Encoding mock HTTP response failed!")
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	for _, test := range tests {
+		b, storage := newBackend(t)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"address":    ts.URL + "/",
+				"api_key":    "abc123",
+				"tls_verify": false,
+			},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"username":         "user",
+				"allow_all_groups": true,
+				"docker_registry":  "docker.example.com",
+				"repositories":     "npm=npm-virtual",
+				"output_template":  "{{ .Username }}:{{ .AccessToken }}",
+			},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "token/test",
+			Storage:   storage,
+			Data:      map[string]interface{}{"format": test.format},
+		})
+		assertLogicalResponse(t, test.expectation, err, resp)
+
+		for _, field := range test.expectedFields {
+			if _, ok := resp.Data[field]; !ok {
+				t.Fatalf("Expected field %s in response, got: %v\n", field, resp.Data)
+			}
+		}
+	}
+}
+
+func TestToken_ReadMetadata(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+			TokenID:     "token-id",
+			AccessToken: "abc123",
+			ExpiresIn:   3600,
+			TokenType:   "Bearer",
+		})
+		if err != nil {
+			t.Fatal("Encoding mock HTTP response failed!")
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"username": "user", "allow_all_groups": true},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	before := time.Now().Add(3600 * time.Second).Truncate(time.Second)
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	expected := map[string]interface{}{
+		"token_id":    "token-id",
+		"username":    "user",
+		"expires_in":  int64(3600),
+		"refreshable": false,
+		"address":     ts.URL + "/",
+	}
+	for field, value := range expected {
+		if resp.Data[field] != value {
+			t.Fatalf("Expected %s to be %v, got: %v\n", field, value, resp.Data[field])
+		}
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, resp.Data["expires_at"].(string))
+	if err != nil {
+		t.Fatalf("Failed to parse expires_at: %v\n", err)
+	}
+	if expiresAt.Before(before) || expiresAt.After(time.Now().Add(3600*time.Second)) {
+		t.Fatalf("Unexpected expires_at: %v\n", expiresAt)
+	}
+}
+
+func TestToken_ReadReferenceToken(t *testing.T) {
+	var description, revokedPath string
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/access/api/v1/tokens" && r.Method == http.MethodPost:
+			var req struct {
+				IncludeReferenceToken bool   `json:"include_reference_token"`
+				Description           string `json:"description"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Unable to decode token request: %v\n", err)
+			}
+			if !req.IncludeReferenceToken {
+				t.Fatal("Expected a reference token to be requested")
+			}
+			description = req.Description
+			body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+				TokenID:        "token-1",
+				AccessToken:    "abc123",
+				ExpiresIn:      3600,
+				Scope:          "applied-permissions/groups:group",
+				TokenType:      "Bearer",
+				ReferenceToken: "cmVmdGtuOjAx",
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		case r.Method == http.MethodDelete:
+			revokedPath = r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/artifactory/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"member_of_groups":        "group",
+			"include_reference_token": true,
+			"description_template":    "vault {{ .RoleName }} for {{ .DisplayName }} ({{ .Metadata.team }})",
+			"metadata":                "team=platform",
+			"skip_validation":         true,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "token/test",
+		Storage:     storage,
+		DisplayName: "token-ci",
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if resp.Data["reference_token"] != "cmVmdGtuOjAx" {
+		t.Fatalf("Expected reference token in response, got: %v\n", resp.Data["reference_token"])
+	}
+	if metadata := resp.Secret.InternalData["role_metadata"].(map[string]string); metadata["team"] != "platform" {
+		t.Fatalf("Expected role metadata in lease, got: %v\n", resp.Secret.InternalData)
+	}
+	if resp.Secret.InternalData["role_version"] != 1 {
+		t.Fatalf("Expected role version in lease, got: %v\n", resp.Secret.InternalData)
+	}
+	if description != "vault test for token-ci (platform)" {
+		t.Fatalf("Expected rendered description, got: %q\n", description)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+		Data:      resp.Data,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if revokedPath != "/access/api/v1/tokens/token-1" {
+		t.Fatalf("Expected token-1 to be revoked through the Access API, got: %s\n", revokedPath)
+	}
+}
+
+func TestToken_DefaultDescription(t *testing.T) {
+	tests := []struct {
+		role     *roleConfig
+		expected string
+	}{
+		{&roleConfig{}, ""},
+		{&roleConfig{Description: "CI builds"}, "CI builds"},
+		{
+			&roleConfig{
+				Description: "CI builds",
+				Tags:        []string{"ci", "maven"},
+				Metadata:    map[string]string{"ticket": "OPS-1", "team": "platform"},
+			},
+			"CI builds; tags: ci, maven; metadata: team=platform, ticket=OPS-1",
+		},
+		{&roleConfig{Metadata: map[string]string{"team": "platform"}}, "metadata: team=platform"},
+		// Truncated on a character boundary
+		{&roleConfig{Description: "a" + strings.Repeat("é", maxDescriptionSize)}, "a" + strings.Repeat("é", (maxDescriptionSize-1)/2)},
+	}
+
+	for _, test := range tests {
+		if description := defaultDescription(test.role); description != test.expected {
+			t.Fatalf("Expected description %q, got: %q\n", test.expected, description)
+		}
+	}
+}
+
+func TestToken_DescriptionLegacyApi(t *testing.T) {
+	var paths []string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+			AccessToken: "abc123",
+			ExpiresIn:   3600,
+			TokenType:   "Bearer",
+		})
+		if err != nil {
+			t.Fatal("Encoding mock HTTP response failed!")
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":         "user",
+			"allow_all_groups": true,
+			"description":      "CI builds",
+			"tags":             "ci",
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if len(paths) != 1 || paths[0] != "/api/security/token" {
+		t.Fatalf("Expected a described role to issue through /api/security/token, got: %v\n", paths)
+	}
+	if resp.Secret.InternalData["access_api"] != false {
+		t.Fatalf("Expected the lease to record the legacy API, got: %v\n", resp.Secret.InternalData["access_api"])
+	}
+}
@@ -0,0 +1,61 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtPermissionService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/permission"
+)
+
+// permissionActions maps the actions a role can grant to their names in the
+// permission target API.
+var permissionActions = map[string]string{
+	"read":     rtPermissionService.ActionRead,
+	"deploy":   rtPermissionService.ActionDeploy,
+	"delete":   rtPermissionService.ActionDelete,
+	"annotate": rtPermissionService.ActionAnnotate,
+}
+
+// createPermissionTarget creates a permission target for a single lease,
+// granting the role's actions on its repositories to the lease's user.
+func (b *backend) createPermissionTarget(ctx context.Context, s logical.Storage, name, username string, role *roleConfig) error {
+	permissionService, err := b.permissionService(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	actions := make([]string, 0, len(role.PermissionActions))
+	for _, action := range role.PermissionActions {
+		actions = append(actions, permissionActions[action])
+	}
+
+	err = permissionService.CreatePermissionTarget(&rtPermissionService.CreatePermissionTargetRequest{
+		Name:            name,
+		Repositories:    role.PermissionRepositories,
+		IncludePatterns: role.PermissionIncludePatterns,
+		ExcludePatterns: role.PermissionExcludePatterns,
+		Users:           map[string][]string{username: actions},
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to create permission target: %v", err)
+	}
+	b.Logger().Info("created permission target", "name", name, "username", username)
+
+	return nil
+}
+
+func (b *backend) deletePermissionTarget(ctx context.Context, s logical.Storage, name string) error {
+	permissionService, err := b.permissionService(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if err := permissionService.DeletePermissionTarget(name); err != nil {
+		return fmt.Errorf("Failed to delete permission target %s: %v", name, err)
+	}
+	b.Logger().Info("deleted permission target", "name", name)
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+func TestPermissionTarget_Lifecycle(t *testing.T) {
+	var requests []string
+	var tokenScope string
+	var grantedActions map[string][]string
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/security/token":
+			tokenScope = r.FormValue("scope")
+			body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+				AccessToken: "abc123",
+				ExpiresIn:   3600,
+				Scope:       "api:*",
+				TokenType:   "Bearer",
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		case r.URL.Path == "/api/security/token/revoke":
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/api/v2/security/permissions/"):
+			if r.Method == http.MethodPut {
+				var target struct {
+					Repo struct {
+						Actions struct {
+							Users map[string][]string `json:"users"`
+						} `json:"actions"`
+					} `json:"repo"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+					t.Fatalf("Unable to decode permission target from request: %v\n", err)
+				}
+				grantedActions = target.Repo.Actions.Users
+			}
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		case strings.HasPrefix(r.URL.Path, "/api/security/users/"):
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Fatalf("Unexpected request path: %s\n", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"user_mode":               "dynamic",
+			"permission_repositories": "libs-release-local",
+			"permission_actions":      "read,deploy",
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+		ID:        "1",
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if tokenScope != "member-of-groups:*" {
+		t.Fatalf("Expected token to be scoped to the user, got: %s\n", tokenScope)
+	}
+	actions := grantedActions["vault-test-1"]
+	if len(actions) != 2 || actions[0] != "read" || actions[1] != "write" {
+		t.Fatalf("Expected read and deploy to be granted to vault-test-1, got: %v\n", grantedActions)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    resp.Secret,
+		Data:      resp.Data,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	expected := []string{
+		"PUT /api/security/users/vault-test-1",
+		"PUT /api/v2/security/permissions/vault-test-1",
+		"DELETE /api/v2/security/permissions/vault-test-1",
+		"DELETE /api/security/users/vault-test-1",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected requests %v, got: %v\n", expected, requests)
+	}
+}
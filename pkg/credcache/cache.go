@@ -0,0 +1,131 @@
+package credcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entries are refreshed once less than this fraction of their lifetime
+// remains, but never later than minRefreshWindow before they expire.
+const (
+	refreshFraction  = 10
+	minRefreshWindow = 30 * time.Second
+)
+
+// Cache stores credentials read from Vault on disk, so that client side
+// helpers invoked once per operation do not create a new token every time.
+type Cache struct {
+	dir string
+	now func() time.Time
+}
+
+type Entry struct {
+	Data      map[string]interface{} `json:"data"`
+	IssuedAt  time.Time              `json:"issued_at"`
+	ExpiresAt time.Time              `json:"expires_at"`
+}
+
+func New(dir string) *Cache {
+	return &Cache{dir: dir, now: time.Now}
+}
+
+// DefaultDir returns the per-user cache directory for the named helper.
+func DefaultDir(name string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// NewEntry creates an entry for data read under a lease of the given
+// duration in seconds.
+func (c *Cache) NewEntry(data map[string]interface{}, leaseDuration int) *Entry {
+	now := c.now()
+	return &Entry{
+		Data:      data,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(time.Duration(leaseDuration) * time.Second),
+	}
+}
+
+// Fresh reports whether the entry can still be used rather than refreshed.
+func (c *Cache) Fresh(e *Entry) bool {
+	window := e.ExpiresAt.Sub(e.IssuedAt) / refreshFraction
+	if window < minRefreshWindow {
+		window = minRefreshWindow
+	}
+	return c.now().Before(e.ExpiresAt.Add(-window))
+}
+
+// Get returns the cached entry for key, or nil if there is none or it is no
+// longer fresh.
+func (c *Cache) Get(key string) (*Entry, error) {
+	raw, err := ioutil.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{}
+	if err := json.Unmarshal(raw, entry); err != nil {
+		// A corrupt entry is treated as a miss and overwritten on refresh
+		return nil, nil
+	}
+	if !c.Fresh(entry) {
+		return nil, nil
+	}
+
+	return entry, nil
+}
+
+// Put stores the entry for key. Entries contain credentials so they are only
+// readable by the current user.
+func (c *Cache) Put(key string, e *Entry) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+func (c *Cache) Delete(key string) error {
+	err := os.Remove(c.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Keys are hashed so that arbitrary strings such as URLs can be used.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, fmt.Sprintf("%s.json", hex.EncodeToString(sum[:])))
+}
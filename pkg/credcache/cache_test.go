@@ -0,0 +1,86 @@
+package credcache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCache_Lifecycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credcache")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := New(dir)
+	cache.now = func() time.Time { return now }
+
+	entry, err := cache.Get("artifactory/token/test")
+	if err != nil || entry != nil {
+		t.Fatalf("Expected cache miss, got: %v %v\n", entry, err)
+	}
+
+	entry = cache.NewEntry(map[string]interface{}{"access_token": "abc123"}, 3600)
+	if err := cache.Put("artifactory/token/test", entry); err != nil {
+		t.Fatalf("Failed to store entry: %v\n", err)
+	}
+
+	info, err := os.Stat(cache.path("artifactory/token/test"))
+	if err != nil {
+		t.Fatalf("Failed to stat cache entry: %v\n", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected cache entry to only be readable by the owner, got: %v\n", info.Mode())
+	}
+
+	entry, err = cache.Get("artifactory/token/test")
+	if err != nil || entry == nil {
+		t.Fatalf("Expected cache hit, got: %v %v\n", entry, err)
+	}
+	if entry.Data["access_token"] != "abc123" {
+		t.Fatalf("Unexpected cached data: %v\n", entry.Data)
+	}
+
+	// Within the last 10% of the lease the entry needs refreshing
+	now = now.Add(55 * time.Minute)
+	entry, err = cache.Get("artifactory/token/test")
+	if err != nil || entry != nil {
+		t.Fatalf("Expected entry near expiry to miss, got: %v %v\n", entry, err)
+	}
+
+	if err := cache.Delete("artifactory/token/test"); err != nil {
+		t.Fatalf("Failed to delete entry: %v\n", err)
+	}
+	if err := cache.Delete("artifactory/token/test"); err != nil {
+		t.Fatalf("Deleting a missing entry should succeed: %v\n", err)
+	}
+}
+
+func TestCache_Fresh(t *testing.T) {
+	issued := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		lease   time.Duration
+		elapsed time.Duration
+		fresh   bool
+	}{
+		{time.Hour, 0, true},
+		{time.Hour, 53 * time.Minute, true},
+		{time.Hour, 54 * time.Minute, false},
+		{time.Minute, 20 * time.Second, true},
+		{time.Minute, 30 * time.Second, false},
+		{0, 0, false},
+	}
+
+	for _, test := range tests {
+		now := issued.Add(test.elapsed)
+		cache := &Cache{now: func() time.Time { return now }}
+		entry := &Entry{IssuedAt: issued, ExpiresAt: issued.Add(test.lease)}
+		if cache.Fresh(entry) != test.fresh {
+			t.Fatalf("Expected fresh=%v for lease %v after %v\n", test.fresh, test.lease, test.elapsed)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package credhelper
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/jsok/vault-plugin-secrets-artifactory/pkg/credcache"
+)
+
+// Source reads access tokens for a role from the Vault Artifactory secrets
+// engine on behalf of client side credential helpers, caching them on disk
+// until shortly before their lease expires.
+type Source struct {
+	Vault *api.Client
+	Mount string
+	Role  string
+	Cache *credcache.Cache
+}
+
+// NewSourceFromEnv configures a Source from the environment:
+//
+//	VAULT_ADDR, VAULT_TOKEN     Vault client configuration, the token
+//	                            falls back to ~/.vault-token
+//	VAULT_ARTIFACTORY_ROLE      Role to request tokens from (required)
+//	VAULT_ARTIFACTORY_MOUNT     Mount path of the secrets engine,
+//	                            defaults to artifactory
+//
+// Tokens are cached in the user's cache directory under the helper name.
+func NewSourceFromEnv(helperName string) (*Source, error) {
+	role := os.Getenv("VAULT_ARTIFACTORY_ROLE")
+	if role == "" {
+		return nil, errors.New("VAULT_ARTIFACTORY_ROLE must be set")
+	}
+	mount := os.Getenv("VAULT_ARTIFACTORY_MOUNT")
+	if mount == "" {
+		mount = "artifactory"
+	}
+
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	if client.Token() == "" {
+		token, err := readTokenFile()
+		if err != nil {
+			return nil, err
+		}
+		client.SetToken(token)
+	}
+
+	cacheDir, err := credcache.DefaultDir(helperName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source{
+		Vault: client,
+		Mount: strings.Trim(mount, "/"),
+		Role:  role,
+		Cache: credcache.New(cacheDir),
+	}, nil
+}
+
+// readTokenFile reads the token left by `vault login`.
+func readTokenFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	token, err := ioutil.ReadFile(filepath.Join(home, ".vault-token"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(token)), err
+}
+
+// Read returns the token response data for the role rendered in the given
+// format, an empty format returns only the raw token.
+func (s *Source) Read(format string) (map[string]interface{}, error) {
+	entry, err := s.Cache.Get(s.cacheKey(format))
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		return entry.Data, nil
+	}
+
+	var data map[string][]string
+	if format != "" {
+		data = map[string][]string{"format": {format}}
+	}
+
+	path := s.Mount + "/token/" + s.Role
+	secret, err := s.Vault.Logical().ReadWithData(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no token returned from %s", path)
+	}
+
+	entry = s.Cache.NewEntry(secret.Data, secret.LeaseDuration)
+	if err := s.Cache.Put(s.cacheKey(format), entry); err != nil {
+		return nil, err
+	}
+
+	return entry.Data, nil
+}
+
+// Forget removes the cached token for the format, so the next Read creates a
+// new one.
+func (s *Source) Forget(format string) error {
+	return s.Cache.Delete(s.cacheKey(format))
+}
+
+func (s *Source) cacheKey(format string) string {
+	key := s.Mount + "/token/" + s.Role
+	if format != "" {
+		key += "?format=" + format
+	}
+	return key
+}
@@ -0,0 +1,203 @@
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	rtHttpClient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// GroupService manages groups through the Artifactory Security REST API.
+type GroupService struct {
+	client     *rtHttpClient.ArtifactoryHttpClient
+	ArtDetails auth.ArtifactoryDetails
+	logger     hclog.Logger
+}
+
+type CreateGroupRequest struct {
+	Name        string
+	Description string
+}
+
+// Group is a group read from Artifactory
+type Group struct {
+	Name            string
+	Description     string
+	AdminPrivileges bool
+}
+
+// group is the Artifactory representation of a group
+type group struct {
+	Name            string `json:"name"`
+	Description     string `json:"description,omitempty"`
+	AutoJoin        bool   `json:"autoJoin"`
+	AdminPrivileges bool   `json:"adminPrivileges"`
+	Realm           string `json:"realm"`
+}
+
+const groupsApiPath = "api/security/groups/"
+
+// ErrGroupsForbidden is returned when the configured credentials cannot read
+// groups, such as those of a project admin.
+var ErrGroupsForbidden = errors.New("not permitted to list groups")
+
+// Groups managed by Artifactory itself, rather than an external directory
+const internalRealm = "internal"
+
+func NewGroupService(client *rtHttpClient.ArtifactoryHttpClient) *GroupService {
+	return &GroupService{client: client, logger: hclog.NewNullLogger()}
+}
+
+func (s *GroupService) SetLogger(logger hclog.Logger) {
+	s.logger = logger
+}
+
+func (s *GroupService) GetArtifactoryDetails() auth.ArtifactoryDetails {
+	return s.ArtDetails
+}
+
+func (s *GroupService) SetArtifactoryDetails(rt auth.ArtifactoryDetails) {
+	s.ArtDetails = rt
+}
+
+func (s *GroupService) CreateGroup(req *CreateGroupRequest) error {
+	if req == nil || req.Name == "" {
+		return fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), groupsApiPath+url.PathEscape(req.Name), nil)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(&group{
+		Name:        req.Name,
+		Description: req.Description,
+		Realm:       internalRealm,
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("creating group", "name", req.Name)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	utils.SetContentType("application/json", &httpClientDetails.Headers)
+	resp, body, err := s.client.SendPut(reqUrl, content, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	s.logger.Debug("created group", "name", req.Name)
+
+	return nil
+}
+
+// GetGroups returns the names of every group in Artifactory.
+func (s *GroupService) GetGroups() ([]string, error) {
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), groupsApiPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, _, err := s.client.SendGet(reqUrl, true, &httpClientDetails)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrGroupsForbidden
+	default:
+		return nil, errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+
+	groups := []group{}
+	if err := json.Unmarshal(body, &groups); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	return names, nil
+}
+
+// GetGroup reads a group, returning nil if it does not exist.
+func (s *GroupService) GetGroup(name string) (*Group, error) {
+	if name == "" {
+		return nil, fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), groupsApiPath+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, _, err := s.client.SendGet(reqUrl, true, &httpClientDetails)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrGroupsForbidden
+	default:
+		return nil, errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+
+	g := &group{}
+	if err := json.Unmarshal(body, g); err != nil {
+		return nil, err
+	}
+
+	return &Group{Name: g.Name, Description: g.Description, AdminPrivileges: g.AdminPrivileges}, nil
+}
+
+// DeleteGroup deletes a group, a group which does not exist is not an error.
+// Artifactory removes the group from any permission target it appears in.
+func (s *GroupService) DeleteGroup(name string) error {
+	if name == "" {
+		return fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), groupsApiPath+url.PathEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("deleting group", "name", name)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, err := s.client.SendDelete(reqUrl, nil, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		s.logger.Debug("group does not exist", "name", name)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	s.logger.Debug("deleted group", "name", name)
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package group
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	"github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+func init() {
+	log.SetLogger(log.NewLogger(log.ERROR, nil))
+}
+
+func newTestGroupService(t *testing.T, url string) *GroupService {
+	rtDetails := auth.NewArtifactoryDetails()
+	rtDetails.SetUrl(url)
+	rtDetails.SetApiKey("fake-api-key")
+
+	client, err := httpclient.ArtifactoryClientBuilder().
+		SetInsecureTls(true).
+		SetArtDetails(&rtDetails).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to create Artifactory client: %v\n", err)
+	}
+
+	groupService := NewGroupService(client)
+	groupService.SetArtifactoryDetails(rtDetails)
+	return groupService
+}
+
+func TestCreateGroup(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		request       *CreateGroupRequest
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			&CreateGroupRequest{Name: "vault-test-1", Description: "test"},
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut {
+					t.Fatalf("Expected PUT but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+groupsApiPath+"vault-test-1" {
+					t.Fatalf("Expected request path to be %svault-test-1, got %s\n", groupsApiPath, r.URL.Path)
+				}
+				g := &group{}
+				if err := json.NewDecoder(r.Body).Decode(g); err != nil {
+					t.Fatalf("Unable to decode group from request: %v\n", err)
+				}
+				if g.AutoJoin || g.Realm != internalRealm || g.Description != "test" {
+					t.Fatalf("Unexpected group: %#v\n", g)
+				}
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			false,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			false,
+			&CreateGroupRequest{Name: "vault-test-1"},
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		err := newTestGroupService(t, ts.URL+"/").CreateGroup(test.request)
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+	}
+}
+
+func TestDeleteGroup(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		name          string
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Fatalf("Expected DELETE but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+groupsApiPath+"vault-test-1" {
+					t.Fatalf("Expected request path to be %svault-test-1, got %s\n", groupsApiPath, r.URL.Path)
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			true, // Already deleted
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		{false, "", nil},
+		{
+			false,
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		err := newTestGroupService(t, ts.URL+"/").DeleteGroup(test.name)
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+	}
+}
+
+func TestGetGroups(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		expectedErr   error
+		expected      []string
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			nil,
+			[]string{"readers", "writers"},
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Fatalf("Expected GET but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+groupsApiPath {
+					t.Fatalf("Expected request path to be /%s, got %s\n", groupsApiPath, r.URL.Path)
+				}
+				w.Write([]byte(`[{"name": "readers", "uri": "https://example.com/readers"}, {"name": "writers"}]`))
+			},
+		},
+		{
+			false,
+			ErrGroupsForbidden,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+		{
+			false,
+			nil,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		groups, err := newTestGroupService(t, ts.URL+"/").GetGroups()
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+		if test.expectedErr != nil && err != test.expectedErr {
+			t.Fatalf("Expected error %v, got: %v\n", test.expectedErr, err)
+		}
+		if len(groups) != len(test.expected) {
+			t.Fatalf("Expected groups %v, got %v\n", test.expected, groups)
+		}
+		for i := range groups {
+			if groups[i] != test.expected[i] {
+				t.Fatalf("Expected groups %v, got %v\n", test.expected, groups)
+			}
+		}
+	}
+}
+
+func TestGetGroup(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + groupsApiPath + "admins":
+			w.Write([]byte(`{"name": "admins", "description": "Administrators", "adminPrivileges": true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	groupService := newTestGroupService(t, ts.URL+"/")
+
+	g, err := groupService.GetGroup("admins")
+	if err != nil {
+		t.Fatalf("Failed to read group: %v\n", err)
+	}
+	if g == nil || !g.AdminPrivileges || g.Description != "Administrators" {
+		t.Fatalf("Unexpected group: %#v\n", g)
+	}
+
+	g, err = groupService.GetGroup("missing")
+	if err != nil || g != nil {
+		t.Fatalf("Expected missing group to be nil, got: %#v, %v\n", g, err)
+	}
+}
@@ -0,0 +1,200 @@
+package permission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	rtHttpClient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// Repository actions which can be granted by a permission target
+const (
+	ActionRead     = "read"
+	ActionDeploy   = "write"
+	ActionDelete   = "delete"
+	ActionAnnotate = "annotate"
+)
+
+// PermissionTargetService manages permission targets through the Artifactory
+// Security REST API. Requires Artifactory 6.6 or later.
+type PermissionTargetService struct {
+	client     *rtHttpClient.ArtifactoryHttpClient
+	ArtDetails auth.ArtifactoryDetails
+	logger     hclog.Logger
+}
+
+type CreatePermissionTargetRequest struct {
+	Name string
+	// Repository keys, or ANY, ANY LOCAL and ANY REMOTE
+	Repositories    []string
+	IncludePatterns []string
+	ExcludePatterns []string
+	// Actions granted to each user
+	Users map[string][]string
+	// Actions granted to each group
+	Groups map[string][]string
+}
+
+// PermissionTarget is the repository section of an existing permission
+// target.
+type PermissionTarget struct {
+	Name            string
+	Repositories    []string
+	IncludePatterns []string
+	ExcludePatterns []string
+}
+
+// permissionTarget is the Artifactory representation of a permission target
+type permissionTarget struct {
+	Name string                 `json:"name"`
+	Repo *repositoryPermissions `json:"repo"`
+}
+
+type repositoryPermissions struct {
+	Repositories    []string           `json:"repositories"`
+	IncludePatterns []string           `json:"include-patterns,omitempty"`
+	ExcludePatterns []string           `json:"exclude-patterns,omitempty"`
+	Actions         *permissionActions `json:"actions"`
+}
+
+type permissionActions struct {
+	Users  map[string][]string `json:"users,omitempty"`
+	Groups map[string][]string `json:"groups,omitempty"`
+}
+
+const permissionsApiPath = "api/v2/security/permissions/"
+
+func NewPermissionTargetService(client *rtHttpClient.ArtifactoryHttpClient) *PermissionTargetService {
+	return &PermissionTargetService{client: client, logger: hclog.NewNullLogger()}
+}
+
+func (s *PermissionTargetService) SetLogger(logger hclog.Logger) {
+	s.logger = logger
+}
+
+func (s *PermissionTargetService) GetArtifactoryDetails() auth.ArtifactoryDetails {
+	return s.ArtDetails
+}
+
+func (s *PermissionTargetService) SetArtifactoryDetails(rt auth.ArtifactoryDetails) {
+	s.ArtDetails = rt
+}
+
+func (s *PermissionTargetService) CreatePermissionTarget(req *CreatePermissionTargetRequest) error {
+	if req == nil || req.Name == "" {
+		return fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), permissionsApiPath+url.PathEscape(req.Name), nil)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(&permissionTarget{
+		Name: req.Name,
+		Repo: &repositoryPermissions{
+			Repositories:    req.Repositories,
+			IncludePatterns: req.IncludePatterns,
+			ExcludePatterns: req.ExcludePatterns,
+			Actions:         &permissionActions{Users: req.Users, Groups: req.Groups},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("creating permission target", "name", req.Name, "repositories", req.Repositories)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	utils.SetContentType("application/json", &httpClientDetails.Headers)
+	resp, body, err := s.client.SendPut(reqUrl, content, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	s.logger.Debug("created permission target", "name", req.Name)
+
+	return nil
+}
+
+// DeletePermissionTarget deletes a permission target, a permission target
+// which does not exist is not an error.
+func (s *PermissionTargetService) DeletePermissionTarget(name string) error {
+	if name == "" {
+		return fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), permissionsApiPath+url.PathEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("deleting permission target", "name", name)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, err := s.client.SendDelete(reqUrl, nil, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		s.logger.Debug("permission target does not exist", "name", name)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	s.logger.Debug("deleted permission target", "name", name)
+
+	return nil
+}
+
+// GetPermissionTarget returns the repository section of a permission target,
+// or nil if the permission target does not exist.
+func (s *PermissionTargetService) GetPermissionTarget(name string) (*PermissionTarget, error) {
+	if name == "" {
+		return nil, fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), permissionsApiPath+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, _, err := s.client.SendGet(reqUrl, true, &httpClientDetails)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+
+	target := &permissionTarget{}
+	if err := json.Unmarshal(body, target); err != nil {
+		return nil, err
+	}
+	if target.Repo == nil {
+		return nil, fmt.Errorf("permission target %s has no repository permissions", name)
+	}
+
+	return &PermissionTarget{
+		Name:            name,
+		Repositories:    target.Repo.Repositories,
+		IncludePatterns: target.Repo.IncludePatterns,
+		ExcludePatterns: target.Repo.ExcludePatterns,
+	}, nil
+}
@@ -0,0 +1,234 @@
+package permission
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	"github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+func init() {
+	log.SetLogger(log.NewLogger(log.ERROR, nil))
+}
+
+func newTestPermissionTargetService(t *testing.T, url string) *PermissionTargetService {
+	rtDetails := auth.NewArtifactoryDetails()
+	rtDetails.SetUrl(url)
+	rtDetails.SetApiKey("fake-api-key")
+
+	client, err := httpclient.ArtifactoryClientBuilder().
+		SetInsecureTls(true).
+		SetArtDetails(&rtDetails).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to create Artifactory client: %v\n", err)
+	}
+
+	permissionService := NewPermissionTargetService(client)
+	permissionService.SetArtifactoryDetails(rtDetails)
+	return permissionService
+}
+
+func TestCreatePermissionTarget(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		request       *CreatePermissionTargetRequest
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			&CreatePermissionTargetRequest{
+				Name:            "vault-test-1",
+				Repositories:    []string{"libs-release-local"},
+				IncludePatterns: []string{"**"},
+				Users:           map[string][]string{"vault-test-1": {ActionRead, ActionDeploy}},
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut {
+					t.Fatalf("Expected PUT but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+permissionsApiPath+"vault-test-1" {
+					t.Fatalf("Expected request path to be %svault-test-1, got %s\n", permissionsApiPath, r.URL.Path)
+				}
+				target := &permissionTarget{}
+				if err := json.NewDecoder(r.Body).Decode(target); err != nil {
+					t.Fatalf("Unable to decode permission target from request: %v\n", err)
+				}
+				if len(target.Repo.Repositories) != 1 || target.Repo.Repositories[0] != "libs-release-local" {
+					t.Fatalf("Unexpected permission target repositories: %v\n", target.Repo.Repositories)
+				}
+				actions := target.Repo.Actions.Users["vault-test-1"]
+				if len(actions) != 2 || actions[0] != "read" || actions[1] != "write" {
+					t.Fatalf("Unexpected permission target actions: %v\n", actions)
+				}
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			true,
+			&CreatePermissionTargetRequest{
+				Name:         "vault-test-1-1",
+				Repositories: []string{"libs-release-local"},
+				Groups:       map[string][]string{"vault-test-1": {ActionRead}},
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				target := &permissionTarget{}
+				if err := json.NewDecoder(r.Body).Decode(target); err != nil {
+					t.Fatalf("Unable to decode permission target from request: %v\n", err)
+				}
+				actions := target.Repo.Actions.Groups["vault-test-1"]
+				if len(actions) != 1 || actions[0] != "read" || len(target.Repo.Actions.Users) != 0 {
+					t.Fatalf("Unexpected permission target actions: %v\n", target.Repo.Actions)
+				}
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			false,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			false,
+			&CreatePermissionTargetRequest{Name: "vault-test-1"},
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		err := newTestPermissionTargetService(t, ts.URL+"/").CreatePermissionTarget(test.request)
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+	}
+}
+
+func TestDeletePermissionTarget(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		name          string
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Fatalf("Expected DELETE but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+permissionsApiPath+"vault-test-1" {
+					t.Fatalf("Expected request path to be %svault-test-1, got %s\n", permissionsApiPath, r.URL.Path)
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			true, // Already deleted
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		{false, "", nil},
+		{
+			false,
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		err := newTestPermissionTargetService(t, ts.URL+"/").DeletePermissionTarget(test.name)
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+	}
+}
+
+func TestGetPermissionTarget(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		exists        bool
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			true,
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/"+permissionsApiPath+"libs" {
+					t.Fatalf("Expected request path to be %slibs, got %s\n", permissionsApiPath, r.URL.Path)
+				}
+				w.Write([]byte(`{
+					"name": "libs",
+					"repo": {
+						"repositories": ["libs-release-local"],
+						"include-patterns": ["org/**"],
+						"actions": {"users": {"admin": ["manage"]}}
+					}
+				}`))
+			},
+		},
+		{
+			true,
+			false, // Permission target does not exist
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		{
+			false,
+			false,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"name": "libs", "build": {"repositories": ["artifactory-build-info"]}}`))
+			},
+		},
+		{
+			false,
+			false,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		target, err := newTestPermissionTargetService(t, ts.URL+"/").GetPermissionTarget("libs")
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+		if test.exists && (target == nil || target.Repositories[0] != "libs-release-local" || target.IncludePatterns[0] != "org/**") {
+			t.Fatalf("Expected permission target to be returned, got: %#v\n", target)
+		}
+		if !test.exists && target != nil {
+			t.Fatalf("Expected no permission target, got: %#v\n", target)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	rtHttpClient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+// ProjectService reads JFrog projects through the Access API.
+type ProjectService struct {
+	client     *rtHttpClient.ArtifactoryHttpClient
+	ArtDetails auth.ArtifactoryDetails
+	logger     hclog.Logger
+}
+
+type Project struct {
+	ProjectKey  string `json:"project_key"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+}
+
+// ErrProjectForbidden is returned when the configured credentials cannot
+// administer a project, and so cannot issue tokens for it.
+var ErrProjectForbidden = errors.New("not permitted to administer project")
+
+const projectsApiPath = "api/v1/projects/"
+
+func NewProjectService(client *rtHttpClient.ArtifactoryHttpClient) *ProjectService {
+	return &ProjectService{client: client, logger: hclog.NewNullLogger()}
+}
+
+func (s *ProjectService) SetLogger(logger hclog.Logger) {
+	s.logger = logger
+}
+
+func (s *ProjectService) GetArtifactoryDetails() auth.ArtifactoryDetails {
+	return s.ArtDetails
+}
+
+func (s *ProjectService) SetArtifactoryDetails(rt auth.ArtifactoryDetails) {
+	s.ArtDetails = rt
+}
+
+// GetProject returns the project with the given key, or nil if it does not
+// exist. Only platform and project admins can read a project.
+func (s *ProjectService) GetProject(key string) (*Project, error) {
+	if key == "" {
+		return nil, fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtTokenService.AccessApiUrl(rtDetails.GetUrl()), projectsApiPath+url.PathEscape(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("reading project", "project", key)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, _, err := s.client.SendGet(reqUrl, true, &httpClientDetails)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, ErrProjectForbidden
+	default:
+		return nil, errorutils.CheckError(errors.New("Access response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+
+	project := &Project{}
+	if err := json.Unmarshal(body, project); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
@@ -0,0 +1,96 @@
+package project
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	"github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+func init() {
+	log.SetLogger(log.NewLogger(log.ERROR, nil))
+}
+
+func TestGetProject(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		expectedErr   error
+		exists        bool
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			nil,
+			true,
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Fatalf("Expected GET but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/access/"+projectsApiPath+"ci" {
+					t.Fatalf("Expected request path to be /access/%sci, got %s\n", projectsApiPath, r.URL.Path)
+				}
+				w.Write([]byte(`{"project_key": "ci", "display_name": "CI"}`))
+			},
+		},
+		{
+			true,
+			nil,
+			false,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		{
+			false,
+			ErrProjectForbidden,
+			false,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+		{
+			false,
+			nil,
+			false,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		rtDetails := auth.NewArtifactoryDetails()
+		rtDetails.SetUrl(ts.URL + "/artifactory/")
+		rtDetails.SetAccessToken("fake-admin-token")
+
+		client, err := httpclient.ArtifactoryClientBuilder().
+			SetInsecureTls(true).
+			SetArtDetails(&rtDetails).
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to create Artifactory client: %v\n", err)
+		}
+
+		projectService := NewProjectService(client)
+		projectService.SetArtifactoryDetails(rtDetails)
+		project, err := projectService.GetProject("ci")
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+		if test.expectedErr != nil && err != test.expectedErr {
+			t.Fatalf("Expected error %v, got: %v\n", test.expectedErr, err)
+		}
+		if test.exists != (project != nil) {
+			t.Fatalf("Expected project to exist: %v, got: %v\n", test.exists, project)
+		}
+	}
+}
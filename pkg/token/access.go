@@ -0,0 +1,113 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// Project scoped tokens, reference tokens and token descriptions are only
+// supported by the JFrog Access API, which is served alongside Artifactory on
+// the platform.
+const accessTokensApiPath = "api/v1/tokens"
+
+// Scopes of the Access API equivalent to member-of-groups scopes
+const (
+	accessGroupsScopePrefix = "applied-permissions/groups:"
+	accessUserScope         = "applied-permissions/user"
+)
+
+// accessCreateTokenRequest is the Access API representation of a
+// CreateTokenRequest.
+type accessCreateTokenRequest struct {
+	GrantType             string `json:"grant_type,omitempty"`
+	Username              string `json:"username,omitempty"`
+	Scope                 string `json:"scope,omitempty"`
+	ExpiresIn             int64  `json:"expires_in"`
+	Refreshable           bool   `json:"refreshable"`
+	ProjectKey            string `json:"project_key,omitempty"`
+	IncludeReferenceToken bool   `json:"include_reference_token,omitempty"`
+	Description           string `json:"description,omitempty"`
+}
+
+// AccessApiUrl returns the base URL of the Access API of the platform an
+// Artifactory address belongs to, e.g. https://example.com/artifactory/
+// becomes https://example.com/access/.
+func AccessApiUrl(artifactoryUrl string) string {
+	base := strings.TrimSuffix(artifactoryUrl, "/")
+	base = strings.TrimSuffix(base, "/artifactory")
+	return base + "/access/"
+}
+
+func (s *AccessTokenService) createAccessToken(req *CreateTokenRequest) (*http.Response, []byte, error) {
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(AccessApiUrl(rtDetails.GetUrl()), accessTokensApiPath, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := json.Marshal(&accessCreateTokenRequest{
+		GrantType:             req.GrantType,
+		Username:              req.Username,
+		Scope:                 accessScope(req.Scope),
+		ExpiresIn:             req.ExpiresIn,
+		Refreshable:           req.Refreshable,
+		ProjectKey:            req.ProjectKey,
+		IncludeReferenceToken: req.IncludeReferenceToken,
+		Description:           req.Description,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	utils.SetContentType("application/json", &httpClientDetails.Headers)
+	return s.client.SendPost(reqUrl, content, &httpClientDetails)
+}
+
+func (s *AccessTokenService) revokeAccessToken(tokenID string) error {
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(AccessApiUrl(rtDetails.GetUrl()), accessTokensApiPath+"/"+url.PathEscape(tokenID), nil)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("revoking access token through the Access API", "token_id", tokenID)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, err := s.client.SendDelete(reqUrl, nil, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	// The token has expired or was already revoked
+	if resp.StatusCode == http.StatusNotFound {
+		s.logger.Debug("access token does not exist", "token_id", tokenID)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return errorutils.CheckError(errors.New("Access response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	s.logger.Debug("revoked access token through the Access API", "token_id", tokenID)
+
+	return nil
+}
+
+// accessScope translates member-of-groups scopes to their Access API
+// equivalent.
+func accessScope(scope string) string {
+	scopes := strings.Fields(scope)
+	for i, s := range scopes {
+		switch {
+		case s == memberOfGroupsScopePrefix+"*":
+			scopes[i] = accessUserScope
+		case strings.HasPrefix(s, memberOfGroupsScopePrefix):
+			scopes[i] = accessGroupsScopePrefix + strings.TrimPrefix(s, memberOfGroupsScopePrefix)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
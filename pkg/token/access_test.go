@@ -0,0 +1,199 @@
+package token
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	"github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+)
+
+func TestAccessApiUrl(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected string
+	}{
+		{"https://example.com/artifactory/", "https://example.com/access/"},
+		{"https://example.com/artifactory", "https://example.com/access/"},
+		{"https://artifactory.example.com/", "https://artifactory.example.com/access/"},
+	}
+
+	for _, test := range tests {
+		if url := AccessApiUrl(test.address); url != test.expected {
+			t.Fatalf("Expected Access API URL %q for %q, got %q\n", test.expected, test.address, url)
+		}
+	}
+}
+
+func TestAccessScope(t *testing.T) {
+	tests := []struct {
+		scope    string
+		expected string
+	}{
+		{"member-of-groups:readers,writers", "applied-permissions/groups:readers,writers"},
+		{"member-of-groups:*", "applied-permissions/user"},
+		{"api:* member-of-groups:readers", "api:* applied-permissions/groups:readers"},
+	}
+
+	for _, test := range tests {
+		if scope := accessScope(test.scope); scope != test.expected {
+			t.Fatalf("Expected Access API scope %q for %q, got %q\n", test.expected, test.scope, scope)
+		}
+	}
+}
+
+func TestProjectToken(t *testing.T) {
+	var revokedPath string
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/access/"+accessTokensApiPath:
+			req := &accessCreateTokenRequest{}
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				t.Fatalf("Unable to decode token request: %v\n", err)
+			}
+			if req.ProjectKey != "ci" || req.Scope != "applied-permissions/groups:readers" {
+				t.Fatalf("Unexpected token request: %#v\n", req)
+			}
+			body, err := json.Marshal(&CreateTokenResponse{
+				TokenID:     "token-1",
+				AccessToken: "fake-access-token",
+				ExpiresIn:   3600,
+				Scope:       "applied-permissions/groups:readers",
+				TokenType:   "Bearer",
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		case r.Method == http.MethodDelete:
+			revokedPath = r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	rtDetails := auth.NewArtifactoryDetails()
+	rtDetails.SetUrl(ts.URL + "/artifactory/")
+	rtDetails.SetAccessToken("fake-admin-token")
+
+	client, err := httpclient.ArtifactoryClientBuilder().
+		SetInsecureTls(true).
+		SetArtDetails(&rtDetails).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to create Artifactory client: %v\n", err)
+	}
+
+	tokenService := NewAccessTokenService(client)
+	tokenService.SetArtifactoryDetails(rtDetails)
+
+	tokenResp, err := tokenService.CreateToken(&CreateTokenRequest{
+		Username:   "user",
+		Scope:      "member-of-groups:readers",
+		ExpiresIn:  3600,
+		ProjectKey: "ci",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create project token: %v\n", err)
+	}
+
+	err = tokenService.RevokeToken(&RevokeTokenRequest{TokenID: tokenResp.TokenID, AccessApi: true})
+	if err != nil {
+		t.Fatalf("Failed to revoke project token: %v\n", err)
+	}
+	if revokedPath != "/access/"+accessTokensApiPath+"/token-1" {
+		t.Fatalf("Expected token-1 to be revoked through the Access API, got: %s\n", revokedPath)
+	}
+
+	if err := tokenService.RevokeToken(&RevokeTokenRequest{Token: "opaque", AccessApi: true}); err == nil {
+		t.Fatal("Expected revoking a project token without an ID to fail")
+	}
+}
+
+func TestReferenceToken(t *testing.T) {
+	tests := []struct {
+		shouldSucceed  bool
+		referenceToken string
+	}{
+		{true, "cmVmdGtuOjAx"},
+		{false, ""}, // Requested but not returned
+	}
+
+	for _, test := range tests {
+		revoked := false
+
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodPost && r.URL.Path == "/access/"+accessTokensApiPath:
+				req := &accessCreateTokenRequest{}
+				if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+					t.Fatalf("Unable to decode token request: %v\n", err)
+				}
+				if !req.IncludeReferenceToken || req.Description != "vault ci" || req.ProjectKey != "" {
+					t.Fatalf("Unexpected token request: %#v\n", req)
+				}
+				body, err := json.Marshal(&CreateTokenResponse{
+					TokenID:        "token-1",
+					AccessToken:    "fake-access-token",
+					ExpiresIn:      3600,
+					Scope:          "applied-permissions/groups:readers",
+					TokenType:      "Bearer",
+					ReferenceToken: test.referenceToken,
+				})
+				if err != nil {
+					t.Fatal("Encoding mock HTTP response failed!")
+				}
+				w.Write(body)
+			case r.Method == http.MethodDelete && r.URL.Path == "/access/"+accessTokensApiPath+"/token-1":
+				revoked = true
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+			}
+		}))
+		defer ts.Close()
+
+		rtDetails := auth.NewArtifactoryDetails()
+		rtDetails.SetUrl(ts.URL + "/artifactory/")
+		rtDetails.SetAccessToken("fake-admin-token")
+
+		client, err := httpclient.ArtifactoryClientBuilder().
+			SetInsecureTls(true).
+			SetArtDetails(&rtDetails).
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to create Artifactory client: %v\n", err)
+		}
+
+		tokenService := NewAccessTokenService(client)
+		tokenService.SetArtifactoryDetails(rtDetails)
+
+		tokenResp, err := tokenService.CreateToken(&CreateTokenRequest{
+			Username:              "user",
+			Scope:                 "member-of-groups:readers",
+			ExpiresIn:             3600,
+			IncludeReferenceToken: true,
+			Description:           "vault ci",
+		})
+		if test.shouldSucceed {
+			if err != nil {
+				t.Fatalf("Expected test to succeed but got error: %v\n", err)
+			}
+			if tokenResp.ReferenceToken != test.referenceToken {
+				t.Fatalf("Expected reference token %q, got %q\n", test.referenceToken, tokenResp.ReferenceToken)
+			}
+		} else {
+			if err == nil {
+				t.Fatal("Expected test to fail but succeeded!")
+			}
+			if !revoked {
+				t.Fatal("Expected the token without a reference token to be revoked")
+			}
+		}
+	}
+}
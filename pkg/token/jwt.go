@@ -0,0 +1,91 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Claims are the claims of an access token which has been decoded but whose
+// signature has not been verified.
+type Claims struct {
+	ID        string   `json:"jti"`
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Scope     string   `json:"scp"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	IssuedAt  int64    `json:"iat"`
+}
+
+// Username returns the name of the user the token was issued to, or an
+// empty string if the token subject is not a user.
+func (c *Claims) Username() string {
+	return TokenInfo{Subject: c.Subject}.Username()
+}
+
+// audience accepts the aud claim as either a single string or a list, which
+// is joined with spaces.
+type audience string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience(single)
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = audience(strings.Join(list, " "))
+	return nil
+}
+
+// ErrNotJWT is returned when parsing the claims of an access token which is
+// not a JWT, such as a reference token.
+var ErrNotJWT = errors.New("access token is not a JWT")
+
+// ParseClaims decodes the claims of an access token without making any
+// external calls. The signature of the token is not verified.
+func ParseClaims(accessToken string) (*Claims, error) {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrNotJWT
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// tokenID extracts the ID (jti claim) of an access token. The token is not
+// verified, an empty string is returned if it cannot be decoded.
+func tokenID(accessToken string) string {
+	claims, err := ParseClaims(accessToken)
+	if err != nil {
+		return ""
+	}
+	return claims.ID
+}
+
+// TokenUsername extracts the name of the user an access token was issued to
+// from its subject. The token is not verified, an empty string is returned if
+// it cannot be decoded.
+func TokenUsername(accessToken string) string {
+	claims, err := ParseClaims(accessToken)
+	if err != nil {
+		return ""
+	}
+	return claims.Username()
+}
@@ -0,0 +1,67 @@
+package token
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func fakeJWT(payload string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"RS256"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestTokenID(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected string
+	}{
+		{fakeJWT(`{"sub":"jfrt@01/users/admin","jti":"2a0e3d8c-40c3-4c36-a1f0-0ad5e0e0a7c1"}`), "2a0e3d8c-40c3-4c36-a1f0-0ad5e0e0a7c1"},
+		{fakeJWT(`{"sub":"jfrt@01/users/admin"}`), ""},
+		{fakeJWT(`not json`), ""},
+		{"opaque-token", ""},
+	}
+
+	for _, test := range tests {
+		if id := tokenID(test.token); id != test.expected {
+			t.Fatalf("Expected token ID %q from %q, got %q\n", test.expected, test.token, id)
+		}
+	}
+}
+
+func TestTokenUsername(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected string
+	}{
+		{fakeJWT(`{"sub":"jfrt@01c1ys5h5jfwm3f4rhrzgs1a09/users/admin","jti":"1"}`), "admin"},
+		{fakeJWT(`{"sub":"jfrt@01c1ys5h5jfwm3f4rhrzgs1a09"}`), ""},
+		{"opaque-token", ""},
+	}
+
+	for _, test := range tests {
+		if username := TokenUsername(test.token); username != test.expected {
+			t.Fatalf("Expected username %q from %q, got %q\n", test.expected, test.token, username)
+		}
+	}
+}
+
+func TestParseClaims_Audience(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected string
+	}{
+		{fakeJWT(`{"aud":"jfrt@*"}`), "jfrt@*"},
+		{fakeJWT(`{"aud":["jfrt@01","jfac@01"]}`), "jfrt@01 jfac@01"},
+		{fakeJWT(`{}`), ""},
+	}
+
+	for _, test := range tests {
+		claims, err := ParseClaims(test.token)
+		if err != nil {
+			t.Fatalf("Failed to decode claims from %q: %v\n", test.token, err)
+		}
+		if string(claims.Audience) != test.expected {
+			t.Fatalf("Expected audience %q from %q, got %q\n", test.expected, test.token, claims.Audience)
+		}
+	}
+}
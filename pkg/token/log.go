@@ -0,0 +1,51 @@
+package token
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+// LogAdapter implements the jfrog client's log.Log interface on top of an
+// hclog.Logger, so that messages from the jfrog client end up in Vault's
+// plugin log.
+type LogAdapter struct {
+	logger hclog.Logger
+}
+
+func NewLogAdapter(logger hclog.Logger) *LogAdapter {
+	return &LogAdapter{logger: logger}
+}
+
+func (l *LogAdapter) GetLogLevel() log.LevelType {
+	switch {
+	case l.logger.IsDebug():
+		return log.DEBUG
+	case l.logger.IsInfo():
+		return log.INFO
+	case l.logger.IsWarn():
+		return log.WARN
+	default:
+		return log.ERROR
+	}
+}
+
+// The level and output of the log are controlled by the hclog.Logger, so
+// these are no-ops.
+func (l *LogAdapter) SetLogLevel(log.LevelType)        {}
+func (l *LogAdapter) SetOutputWriter(writer io.Writer) {}
+func (l *LogAdapter) SetLogsWriter(writer io.Writer)   {}
+
+func (l *LogAdapter) Debug(a ...interface{})  { l.logger.Debug(sprint(a...)) }
+func (l *LogAdapter) Info(a ...interface{})   { l.logger.Info(sprint(a...)) }
+func (l *LogAdapter) Warn(a ...interface{})   { l.logger.Warn(sprint(a...)) }
+func (l *LogAdapter) Error(a ...interface{})  { l.logger.Error(sprint(a...)) }
+func (l *LogAdapter) Output(a ...interface{}) { l.logger.Info(sprint(a...)) }
+
+// sprint formats its arguments the same way as the jfrog logger does.
+func sprint(a ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(a...), "\n")
+}
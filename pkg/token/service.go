@@ -6,23 +6,20 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
+	"strings"
 
+	hclog "github.com/hashicorp/go-hclog"
 	"github.com/jfrog/jfrog-client-go/artifactory/auth"
 	rtHttpClient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
 	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
 	clientutils "github.com/jfrog/jfrog-client-go/utils"
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
-	"github.com/jfrog/jfrog-client-go/utils/log"
 )
 
-func init() {
-	log.SetLogger(log.NewLogger(log.WARN, os.Stderr))
-}
-
 type AccessTokenService struct {
 	client     *rtHttpClient.ArtifactoryHttpClient
 	ArtDetails auth.ArtifactoryDetails
+	logger     hclog.Logger
 }
 
 type CreateTokenRequest struct {
@@ -31,26 +28,72 @@ type CreateTokenRequest struct {
 	Scope       string
 	ExpiresIn   int64
 	Refreshable bool
+	// Tokens for a JFrog project are created through the Access API
+	ProjectKey string
+	// Reference tokens and descriptions are only supported by the Access API
+	IncludeReferenceToken bool
+	Description           string
+}
+
+// AccessApi reports whether the token is created through the Access API,
+// such tokens must also be revoked through it.
+func (r *CreateTokenRequest) AccessApi() bool {
+	return r.ProjectKey != "" || r.IncludeReferenceToken || r.Description != ""
 }
 
 type CreateTokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	ExpiresIn    int64  `json:"expires_in"`
-	Scope        string `json:"scope"`
-	TokenType    string `json:"token_type"`
-	RefreshToken string `json:"refresh_token"`
+	TokenID        string `json:"token_id"`
+	AccessToken    string `json:"access_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+	Scope          string `json:"scope"`
+	TokenType      string `json:"token_type"`
+	RefreshToken   string `json:"refresh_token"`
+	Audience       string `json:"audience"`
+	ReferenceToken string `json:"reference_token"`
+}
+
+type TokenInfo struct {
+	TokenID     string `json:"token_id"`
+	Issuer      string `json:"issuer"`
+	Subject     string `json:"subject"`
+	Expiry      int64  `json:"expiry"`
+	Refreshable bool   `json:"refreshable"`
+	IssuedAt    int64  `json:"issued_at"`
+}
+
+// Username returns the name of the user the token was issued to, or an
+// empty string if the token subject is not a user.
+func (t TokenInfo) Username() string {
+	i := strings.LastIndex(t.Subject, tokenSubjectUsersPath)
+	if i < 0 {
+		return ""
+	}
+	return t.Subject[i+len(tokenSubjectUsersPath):]
+}
+
+type GetTokensResponse struct {
+	Tokens []TokenInfo `json:"tokens"`
 }
 
 type RevokeTokenRequest struct {
 	Token   string
 	TokenID string
+	// Tokens created through the Access API are revoked by ID through it
+	AccessApi bool
 }
 
 const tokenApiPath = "api/security/token"
 const tokenRevokeApiPath = tokenApiPath + "/revoke"
 
+// Token subjects take the form <service-id>/users/<username>
+const tokenSubjectUsersPath = "/users/"
+
 func NewAccessTokenService(client *rtHttpClient.ArtifactoryHttpClient) *AccessTokenService {
-	return &AccessTokenService{client: client}
+	return &AccessTokenService{client: client, logger: hclog.NewNullLogger()}
+}
+
+func (s *AccessTokenService) SetLogger(logger hclog.Logger) {
+	s.logger = logger
 }
 
 func (s *AccessTokenService) GetArtifactoryDetails() auth.ArtifactoryDetails {
@@ -66,10 +109,61 @@ func (s *AccessTokenService) CreateToken(req *CreateTokenRequest) (*CreateTokenR
 		return nil, fmt.Errorf("Empty request")
 	}
 
+	s.logger.Debug("creating access token", "username", req.Username, "scope", req.Scope, "expires_in", req.ExpiresIn, "project", req.ProjectKey, "access_api", req.AccessApi())
+
+	var resp *http.Response
+	var body []byte
+	var err error
+	if req.AccessApi() {
+		resp, body, err = s.createAccessToken(req)
+	} else {
+		resp, body, err = s.createToken(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+
+	tokenResp := &CreateTokenResponse{}
+	if err := json.Unmarshal(body, tokenResp); err != nil {
+		return nil, err
+	}
+
+	claims, err := ParseClaims(tokenResp.AccessToken)
+	switch {
+	case err == ErrNotJWT:
+		claims = nil
+	case err != nil:
+		return nil, s.revokeInvalidToken(req, tokenResp, fmt.Errorf("Failed to decode access token: %v", err))
+	}
+	if err := validateToken(req, tokenResp, claims); err != nil {
+		return nil, s.revokeInvalidToken(req, tokenResp, fmt.Errorf("Artifactory returned an access token broader than requested: %v", err))
+	}
+	if req.IncludeReferenceToken && tokenResp.ReferenceToken == "" {
+		return nil, s.revokeInvalidToken(req, tokenResp, fmt.Errorf("Artifactory did not return the requested reference token"))
+	}
+
+	// Older Artifactory versions only include these within the token
+	if claims != nil {
+		if tokenResp.TokenID == "" {
+			tokenResp.TokenID = claims.ID
+		}
+		if tokenResp.Audience == "" {
+			tokenResp.Audience = string(claims.Audience)
+		}
+	}
+	s.logger.Debug("created access token", "username", req.Username, "token_id", tokenResp.TokenID)
+
+	return tokenResp, nil
+}
+
+func (s *AccessTokenService) createToken(req *CreateTokenRequest) (*http.Response, []byte, error) {
 	rtDetails := s.GetArtifactoryDetails()
 	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), tokenApiPath, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	data := url.Values{}
@@ -81,10 +175,35 @@ func (s *AccessTokenService) CreateToken(req *CreateTokenRequest) (*CreateTokenR
 	}
 	data.Set("expires_in", fmt.Sprintf("%v", req.ExpiresIn))
 	data.Set("refreshable", fmt.Sprintf("%v", req.Refreshable))
-	log.Debug("Sending HTTP POST Form data: ", data.Encode())
 
 	httpClientDetails := rtDetails.CreateHttpClientDetails()
-	resp, body, err := s.client.SendPostForm(reqUrl, data, &httpClientDetails)
+	return s.client.SendPostForm(reqUrl, data, &httpClientDetails)
+}
+
+// revokeInvalidToken revokes a token which failed validation so that it is
+// never handed out, returning the validation error.
+func (s *AccessTokenService) revokeInvalidToken(req *CreateTokenRequest, tokenResp *CreateTokenResponse, err error) error {
+	s.logger.Warn("revoking invalid access token", "token_id", tokenResp.TokenID, "error", err)
+	revokeReq := &RevokeTokenRequest{Token: tokenResp.AccessToken}
+	if req.AccessApi() {
+		revokeReq.TokenID = tokenResp.TokenID
+		revokeReq.AccessApi = true
+	}
+	if revokeErr := s.RevokeToken(revokeReq); revokeErr != nil {
+		return fmt.Errorf("%v, revoking the token failed: %v", err, revokeErr)
+	}
+	return err
+}
+
+func (s *AccessTokenService) GetTokens() (*GetTokensResponse, error) {
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), tokenApiPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, _, err := s.client.SendGet(reqUrl, true, &httpClientDetails)
 	if err != nil {
 		return nil, err
 	}
@@ -92,18 +211,25 @@ func (s *AccessTokenService) CreateToken(req *CreateTokenRequest) (*CreateTokenR
 		return nil, errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
 	}
 
-	tokenResp := &CreateTokenResponse{}
-	if err := json.Unmarshal(body, tokenResp); err != nil {
+	tokensResp := &GetTokensResponse{}
+	if err := json.Unmarshal(body, tokensResp); err != nil {
 		return nil, err
 	}
 
-	return tokenResp, nil
+	return tokensResp, nil
 }
 
 func (s *AccessTokenService) RevokeToken(req *RevokeTokenRequest) error {
 	if req.Token == "" && req.TokenID == "" {
 		return fmt.Errorf("Empty request")
 	}
+	if req.AccessApi {
+		tokenID := revokeTokenID(req)
+		if tokenID == "" {
+			return fmt.Errorf("Access API tokens can only be revoked by ID")
+		}
+		return s.revokeAccessToken(tokenID)
+	}
 
 	rtDetails := s.GetArtifactoryDetails()
 	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), tokenRevokeApiPath, nil)
@@ -114,7 +240,7 @@ func (s *AccessTokenService) RevokeToken(req *RevokeTokenRequest) error {
 	data := url.Values{}
 	data.Set("token", req.Token)
 	data.Set("token_id", req.TokenID)
-	log.Debug("Sending HTTP POST Form data: ", data.Encode())
+	s.logger.Debug("revoking access token", "token_id", revokeTokenID(req))
 
 	httpClientDetails := rtDetails.CreateHttpClientDetails()
 	resp, body, err := s.client.SendPostForm(reqUrl, data, &httpClientDetails)
@@ -124,12 +250,22 @@ func (s *AccessTokenService) RevokeToken(req *RevokeTokenRequest) error {
 
 	// This usually means that the token is not revocable
 	if resp.StatusCode == http.StatusInternalServerError {
-		log.Info("Revoke Token failed, token may not be recovable")
+		s.logger.Warn("revoke token failed, token may not be revocable", "token_id", revokeTokenID(req))
 		return nil
 	}
 	if resp.StatusCode != http.StatusOK {
 		return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
 	}
+	s.logger.Debug("revoked access token", "token_id", revokeTokenID(req))
 
 	return nil
 }
+
+// revokeTokenID identifies the token being revoked for logging purposes,
+// the token itself must never be logged.
+func revokeTokenID(req *RevokeTokenRequest) string {
+	if req.TokenID != "" {
+		return req.TokenID
+	}
+	return tokenID(req.Token)
+}
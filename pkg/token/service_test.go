@@ -4,16 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
+	hclog "github.com/hashicorp/go-hclog"
 	"github.com/jfrog/jfrog-client-go/artifactory/auth"
 	"github.com/jfrog/jfrog-client-go/artifactory/httpclient"
 	"github.com/jfrog/jfrog-client-go/utils/log"
 )
 
 func init() {
-	log.SetLogger(log.NewLogger(log.DEBUG, os.Stderr))
+	log.SetLogger(NewLogAdapter(hclog.New(&hclog.LoggerOptions{Level: hclog.Trace})))
 }
 
 func TestCreateToken(t *testing.T) {
@@ -42,7 +42,7 @@ func TestCreateToken(t *testing.T) {
 				body, err := json.Marshal(&CreateTokenResponse{
 					AccessToken: "fake-access-token",
 					ExpiresIn:   3600,
-					Scope:       "api:* member-of-groups:readers,PowerUser",
+					Scope:       "api:* member-of-groups:reader,PowerUser",
 					TokenType:   "Bearer",
 				})
 				if err != nil {
@@ -175,3 +175,89 @@ func TestRevokeToken(t *testing.T) {
 		}
 	}
 }
+
+func TestGetTokens(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		expectedCount int
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			2,
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Fatalf("Expected GET but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+tokenApiPath {
+					t.Fatalf("Expected request path to be %s, got %s\n", tokenApiPath, r.URL.Path)
+				}
+				body, err := json.Marshal(&GetTokensResponse{
+					Tokens: []TokenInfo{
+						{TokenID: "token-1", Subject: "jfrt@01/users/user1"},
+						{TokenID: "token-2", Subject: "jfrt@01/users/user2"},
+					},
+				})
+				if err != nil {
+					t.Fatal("Encoding mock HTTP response failed!")
+				}
+				w.Write(body)
+			},
+		},
+		{
+			false,
+			0,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		rtDetails := auth.NewArtifactoryDetails()
+		rtDetails.SetUrl(ts.URL + "/")
+		rtDetails.SetApiKey("fake-api-key")
+
+		client, err := httpclient.ArtifactoryClientBuilder().
+			SetInsecureTls(true).
+			SetArtDetails(&rtDetails).
+			Build()
+		if err != nil {
+			t.Fatalf("Failed to create Artifactory client: %v\n", err)
+		}
+
+		tokenService := NewAccessTokenService(client)
+		tokenService.SetArtifactoryDetails(rtDetails)
+		resp, err := tokenService.GetTokens()
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+		if test.shouldSucceed && len(resp.Tokens) != test.expectedCount {
+			t.Fatalf("Expected %d tokens, got: %v\n", test.expectedCount, resp.Tokens)
+		}
+	}
+}
+
+func TestTokenInfo_Username(t *testing.T) {
+	tests := []struct {
+		subject  string
+		username string
+	}{
+		{"jfrt@01c1ys5h5jfwm3f4rhrzgs1a09/users/admin", "admin"},
+		{"jfrt@01c1ys5h5jfwm3f4rhrzgs1a09/users/vault-role-id", "vault-role-id"},
+		{"jfrt@01c1ys5h5jfwm3f4rhrzgs1a09", ""},
+	}
+
+	for _, test := range tests {
+		token := TokenInfo{Subject: test.subject}
+		if token.Username() != test.username {
+			t.Fatalf("Expected username %q from subject %q, got %q\n", test.username, test.subject, token.Username())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Artifactory grants api:* to every token scoped to groups
+const implicitApiScope = "api:*"
+
+const memberOfGroupsScopePrefix = "member-of-groups:"
+
+// validateToken checks that the token Artifactory returned grants no more
+// than was requested. Claims are only checked when the token is a JWT.
+func validateToken(req *CreateTokenRequest, resp *CreateTokenResponse, claims *Claims) error {
+	if err := validateScope(req.Scope, resp.Scope); err != nil {
+		return err
+	}
+	if req.ExpiresIn > 0 && (resp.ExpiresIn <= 0 || resp.ExpiresIn > req.ExpiresIn) {
+		return fmt.Errorf("expires in %d seconds, requested %d", resp.ExpiresIn, req.ExpiresIn)
+	}
+
+	if claims == nil {
+		return nil
+	}
+
+	if claims.ID == "" {
+		return fmt.Errorf("token has no ID")
+	}
+	if resp.TokenID != "" && claims.ID != resp.TokenID {
+		return fmt.Errorf("token ID %s does not match response token ID %s", claims.ID, resp.TokenID)
+	}
+	if claims.Issuer != "" && !strings.HasPrefix(claims.Subject, claims.Issuer+"/") {
+		return fmt.Errorf("subject %s was not issued by %s", claims.Subject, claims.Issuer)
+	}
+	if req.Username != "" && claims.Username() != req.Username {
+		return fmt.Errorf("subject %s does not match requested username %s", claims.Subject, req.Username)
+	}
+	if claims.Scope != "" {
+		if err := validateScope(req.Scope, claims.Scope); err != nil {
+			return err
+		}
+	}
+	if req.ExpiresIn > 0 {
+		if claims.ExpiresAt <= 0 {
+			return fmt.Errorf("token does not expire, requested expiry in %d seconds", req.ExpiresIn)
+		}
+		if claims.IssuedAt > 0 && claims.ExpiresAt-claims.IssuedAt > req.ExpiresIn {
+			return fmt.Errorf("token expires %d seconds after issue, requested %d", claims.ExpiresAt-claims.IssuedAt, req.ExpiresIn)
+		}
+	}
+
+	return nil
+}
+
+// validateScope checks that every scope token granted was requested. Groups
+// are compared case insensitively, as Artifactory group names are.
+func validateScope(requested, granted string) error {
+	requestedGroups := map[string]bool{}
+	requestedScopes := map[string]bool{}
+	for _, scope := range strings.Fields(requested) {
+		if strings.HasPrefix(scope, memberOfGroupsScopePrefix) {
+			for _, group := range scopeGroups(scope) {
+				requestedGroups[strings.ToLower(group)] = true
+			}
+			continue
+		}
+		requestedScopes[scope] = true
+	}
+
+	for _, scope := range strings.Fields(granted) {
+		if scope == implicitApiScope || requestedScopes[scope] {
+			continue
+		}
+		// Access API tokens carry the equivalent applied-permissions scopes
+		if scope == accessUserScope {
+			if !requestedGroups["*"] {
+				return fmt.Errorf("scope %s was not requested", scope)
+			}
+			continue
+		}
+		if strings.HasPrefix(scope, accessGroupsScopePrefix) {
+			scope = memberOfGroupsScopePrefix + strings.TrimPrefix(scope, accessGroupsScopePrefix)
+		}
+		if !strings.HasPrefix(scope, memberOfGroupsScopePrefix) {
+			return fmt.Errorf("scope %s was not requested", scope)
+		}
+		if requestedGroups["*"] {
+			continue
+		}
+		for _, group := range scopeGroups(scope) {
+			if !requestedGroups[strings.ToLower(group)] {
+				return fmt.Errorf("membership of group %s was not requested", group)
+			}
+		}
+	}
+
+	return nil
+}
+
+func scopeGroups(scope string) []string {
+	groups := strings.TrimPrefix(scope, memberOfGroupsScopePrefix)
+	if groups == "" {
+		return nil
+	}
+	return strings.Split(groups, ",")
+}
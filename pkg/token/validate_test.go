@@ -0,0 +1,112 @@
+package token
+
+import (
+	"testing"
+)
+
+func TestValidateToken(t *testing.T) {
+	request := &CreateTokenRequest{
+		Username:  "user",
+		Scope:     "member-of-groups:readers,PowerUser",
+		ExpiresIn: 3600,
+	}
+
+	tests := []struct {
+		shouldSucceed bool
+		request       *CreateTokenRequest
+		response      *CreateTokenResponse
+	}{
+		// Opaque tokens only have their response checked
+		{true, request, &CreateTokenResponse{AccessToken: "opaque", ExpiresIn: 3600, Scope: "api:* member-of-groups:readers,poweruser"}},
+		{true, request, &CreateTokenResponse{AccessToken: "opaque", ExpiresIn: 60, Scope: "api:* member-of-groups:readers"}},
+		{false, request, &CreateTokenResponse{AccessToken: "opaque", ExpiresIn: 7200, Scope: "api:* member-of-groups:readers"}},
+		{false, request, &CreateTokenResponse{AccessToken: "opaque", ExpiresIn: 0, Scope: "api:* member-of-groups:readers"}},
+		{false, request, &CreateTokenResponse{AccessToken: "opaque", ExpiresIn: 3600, Scope: "api:* member-of-groups:admins"}},
+		{false, request, &CreateTokenResponse{AccessToken: "opaque", ExpiresIn: 3600, Scope: "applied-permissions/admin"}},
+		{true, &CreateTokenRequest{Scope: "member-of-groups:*"}, &CreateTokenResponse{AccessToken: "opaque", Scope: "api:* member-of-groups:admins"}},
+		{
+			true,
+			request,
+			&CreateTokenResponse{
+				AccessToken: fakeJWT(`{"jti":"1","iss":"jfrt@01","sub":"jfrt@01/users/user","scp":"api:* member-of-groups:readers","iat":1000,"exp":4600}`),
+				ExpiresIn:   3600,
+				Scope:       "api:* member-of-groups:readers",
+			},
+		},
+		{
+			false, // Subject does not match
+			request,
+			&CreateTokenResponse{
+				AccessToken: fakeJWT(`{"jti":"1","iss":"jfrt@01","sub":"jfrt@01/users/admin","scp":"api:* member-of-groups:readers","iat":1000,"exp":4600}`),
+				ExpiresIn:   3600,
+			},
+		},
+		{
+			false, // Subject not issued by the issuer
+			request,
+			&CreateTokenResponse{
+				AccessToken: fakeJWT(`{"jti":"1","iss":"jfrt@02","sub":"jfrt@01/users/user","iat":1000,"exp":4600}`),
+				ExpiresIn:   3600,
+			},
+		},
+		{
+			false, // Scope claim is broader than the response
+			request,
+			&CreateTokenResponse{
+				AccessToken: fakeJWT(`{"jti":"1","iss":"jfrt@01","sub":"jfrt@01/users/user","scp":"api:* member-of-groups:admins","iat":1000,"exp":4600}`),
+				ExpiresIn:   3600,
+				Scope:       "api:* member-of-groups:readers",
+			},
+		},
+		{
+			false, // Expiry claim is later than requested
+			request,
+			&CreateTokenResponse{
+				AccessToken: fakeJWT(`{"jti":"1","iss":"jfrt@01","sub":"jfrt@01/users/user","iat":1000,"exp":8200}`),
+				ExpiresIn:   3600,
+			},
+		},
+		{
+			false, // Token does not expire
+			request,
+			&CreateTokenResponse{
+				AccessToken: fakeJWT(`{"jti":"1","iss":"jfrt@01","sub":"jfrt@01/users/user"}`),
+				ExpiresIn:   3600,
+			},
+		},
+		{
+			false, // Token ID does not match
+			request,
+			&CreateTokenResponse{
+				TokenID:     "2",
+				AccessToken: fakeJWT(`{"jti":"1","iss":"jfrt@01","sub":"jfrt@01/users/user","iat":1000,"exp":4600}`),
+				ExpiresIn:   3600,
+			},
+		},
+		{
+			false, // Token has no ID
+			request,
+			&CreateTokenResponse{
+				AccessToken: fakeJWT(`{"iss":"jfrt@01","sub":"jfrt@01/users/user","iat":1000,"exp":4600}`),
+				ExpiresIn:   3600,
+			},
+		},
+	}
+
+	for i, test := range tests {
+		claims, err := ParseClaims(test.response.AccessToken)
+		if err == ErrNotJWT {
+			claims = nil
+		} else if err != nil {
+			t.Fatalf("Failed to parse claims in test %d: %v\n", i, err)
+		}
+
+		err = validateToken(test.request, test.response, claims)
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test %d to succeed but got error: %v\n", i, err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatalf("Expected test %d to fail but succeeded!\n", i)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	rtHttpClient "github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// UserService manages users through the Artifactory Security REST API.
+type UserService struct {
+	client     *rtHttpClient.ArtifactoryHttpClient
+	ArtDetails auth.ArtifactoryDetails
+	logger     hclog.Logger
+}
+
+type CreateUserRequest struct {
+	Name     string
+	Email    string
+	Password string
+	Groups   []string
+}
+
+// User is a user read from Artifactory
+type User struct {
+	Name   string
+	Admin  bool
+	Groups []string
+}
+
+// user is the Artifactory representation of a user
+type user struct {
+	Name                     string   `json:"name"`
+	Email                    string   `json:"email"`
+	Password                 string   `json:"password,omitempty"`
+	Admin                    bool     `json:"admin"`
+	ProfileUpdatable         bool     `json:"profileUpdatable"`
+	DisableUIAccess          bool     `json:"disableUIAccess"`
+	InternalPasswordDisabled bool     `json:"internalPasswordDisabled"`
+	Groups                   []string `json:"groups"`
+}
+
+const usersApiPath = "api/security/users/"
+
+func NewUserService(client *rtHttpClient.ArtifactoryHttpClient) *UserService {
+	return &UserService{client: client, logger: hclog.NewNullLogger()}
+}
+
+func (s *UserService) SetLogger(logger hclog.Logger) {
+	s.logger = logger
+}
+
+func (s *UserService) GetArtifactoryDetails() auth.ArtifactoryDetails {
+	return s.ArtDetails
+}
+
+func (s *UserService) SetArtifactoryDetails(rt auth.ArtifactoryDetails) {
+	s.ArtDetails = rt
+}
+
+// CreateUser creates a user which can only be used through access tokens,
+// it cannot log in with its password or access the UI.
+func (s *UserService) CreateUser(req *CreateUserRequest) error {
+	if req == nil || req.Name == "" {
+		return fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), usersApiPath+url.PathEscape(req.Name), nil)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(&user{
+		Name:                     req.Name,
+		Email:                    req.Email,
+		Password:                 req.Password,
+		DisableUIAccess:          true,
+		InternalPasswordDisabled: true,
+		Groups:                   req.Groups,
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("creating user", "username", req.Name, "groups", req.Groups)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	utils.SetContentType("application/json", &httpClientDetails.Headers)
+	resp, body, err := s.client.SendPut(reqUrl, content, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	s.logger.Debug("created user", "username", req.Name)
+
+	return nil
+}
+
+// GetUser reads a user, returning nil if it does not exist.
+func (s *UserService) GetUser(name string) (*User, error) {
+	if name == "" {
+		return nil, fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), usersApiPath+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, _, err := s.client.SendGet(reqUrl, true, &httpClientDetails)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+
+	u := &user{}
+	if err := json.Unmarshal(body, u); err != nil {
+		return nil, err
+	}
+
+	return &User{Name: u.Name, Admin: u.Admin, Groups: u.Groups}, nil
+}
+
+// DeleteUser deletes a user, a user which does not exist is not an error.
+func (s *UserService) DeleteUser(name string) error {
+	if name == "" {
+		return fmt.Errorf("Empty request")
+	}
+
+	rtDetails := s.GetArtifactoryDetails()
+	reqUrl, err := utils.BuildArtifactoryUrl(rtDetails.GetUrl(), usersApiPath+url.PathEscape(name), nil)
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("deleting user", "username", name)
+
+	httpClientDetails := rtDetails.CreateHttpClientDetails()
+	resp, body, err := s.client.SendDelete(reqUrl, nil, &httpClientDetails)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		s.logger.Debug("user does not exist", "username", name)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return errorutils.CheckError(errors.New("Artifactory response: " + resp.Status + "\n" + clientutils.IndentJson(body)))
+	}
+	s.logger.Debug("deleted user", "username", name)
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jfrog/jfrog-client-go/artifactory/auth"
+	"github.com/jfrog/jfrog-client-go/artifactory/httpclient"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+func init() {
+	log.SetLogger(log.NewLogger(log.ERROR, nil))
+}
+
+func newTestUserService(t *testing.T, url string) *UserService {
+	rtDetails := auth.NewArtifactoryDetails()
+	rtDetails.SetUrl(url)
+	rtDetails.SetApiKey("fake-api-key")
+
+	client, err := httpclient.ArtifactoryClientBuilder().
+		SetInsecureTls(true).
+		SetArtDetails(&rtDetails).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to create Artifactory client: %v\n", err)
+	}
+
+	userService := NewUserService(client)
+	userService.SetArtifactoryDetails(rtDetails)
+	return userService
+}
+
+func TestCreateUser(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		request       *CreateUserRequest
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			&CreateUserRequest{
+				Name:     "vault-test-1",
+				Email:    "vault-test-1@vault.invalid",
+				Password: "password",
+				Groups:   []string{"readers"},
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut {
+					t.Fatalf("Expected PUT but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+usersApiPath+"vault-test-1" {
+					t.Fatalf("Expected request path to be %svault-test-1, got %s\n", usersApiPath, r.URL.Path)
+				}
+				u := &user{}
+				if err := json.NewDecoder(r.Body).Decode(u); err != nil {
+					t.Fatalf("Unable to decode user from request: %v\n", err)
+				}
+				if u.Admin || !u.DisableUIAccess || !u.InternalPasswordDisabled {
+					t.Fatalf("Expected a non-admin token only user, got: %#v\n", u)
+				}
+				if len(u.Groups) != 1 || u.Groups[0] != "readers" {
+					t.Fatalf("Expected user groups to be [readers], got: %v\n", u.Groups)
+				}
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			false,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			false,
+			&CreateUserRequest{Name: "vault-test-1"},
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		err := newTestUserService(t, ts.URL+"/").CreateUser(test.request)
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	tests := []struct {
+		shouldSucceed bool
+		name          string
+		handler       http.HandlerFunc
+	}{
+		{
+			true,
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Fatalf("Expected DELETE but got request with method: %s\n", r.Method)
+				}
+				if r.URL.Path != "/"+usersApiPath+"vault-test-1" {
+					t.Fatalf("Expected request path to be %svault-test-1, got %s\n", usersApiPath, r.URL.Path)
+				}
+				w.WriteHeader(http.StatusOK)
+			},
+		},
+		{
+			true, // Already deleted
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+		},
+		{false, "", nil},
+		{
+			false,
+			"vault-test-1",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		err := newTestUserService(t, ts.URL+"/").DeleteUser(test.name)
+		if test.shouldSucceed && err != nil {
+			t.Fatalf("Expected test to succeed but got error: %v\n", err)
+		}
+		if !test.shouldSucceed && err == nil {
+			t.Fatal("Expected test to fail but succeeded!")
+		}
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + usersApiPath + "admin":
+			w.Write([]byte(`{"name": "admin", "admin": true, "groups": ["readers"]}`))
+		case "/" + usersApiPath + "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	userService := newTestUserService(t, ts.URL+"/")
+
+	u, err := userService.GetUser("admin")
+	if err != nil {
+		t.Fatalf("Failed to read user: %v\n", err)
+	}
+	if u == nil || !u.Admin || len(u.Groups) != 1 {
+		t.Fatalf("Unexpected user: %#v\n", u)
+	}
+
+	u, err = userService.GetUser("missing")
+	if err != nil || u != nil {
+		t.Fatalf("Expected missing user to be nil, got: %#v, %v\n", u, err)
+	}
+
+	if _, err := userService.GetUser("broken"); err == nil {
+		t.Fatal("Expected reading user to fail")
+	}
+}
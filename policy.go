@@ -0,0 +1,212 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtGroupService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/group"
+	rtUserService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/user"
+)
+
+// roleScope describes the broadest privileges the tokens of a role carry.
+type roleScope struct {
+	// Tokens are scoped to every group of the user
+	AllGroups bool
+	// Why tokens carry admin privileges, empty if they do not
+	AdminReason string
+}
+
+// How long admin privileges of users and groups are cached, so that
+// checking forbid_admin_scopes does not look them up on every issuance.
+const adminCacheTTL = time.Minute
+
+// adminCache holds whether users and groups have admin privileges, keyed
+// by "group/<name>" or "user/<name>" and shared by the auditors of a backend
+// until the config changes.
+type adminCache struct {
+	lock    sync.Mutex
+	entries map[string]adminEntry
+}
+
+type adminEntry struct {
+	admin   bool
+	expires time.Time
+}
+
+func (c *adminCache) get(key string) (bool, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return false, false
+	}
+	return entry.admin, true
+}
+
+func (c *adminCache) put(key string, admin bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]adminEntry{}
+	}
+	c.entries[key] = adminEntry{admin: admin, expires: time.Now().Add(adminCacheTTL)}
+}
+
+func (c *adminCache) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries = nil
+}
+
+// scopeAuditor finds the privileges of role tokens through the backend's
+// cache of the users and groups it reads.
+type scopeAuditor struct {
+	groupService *rtGroupService.GroupService
+	userService  *rtUserService.UserService
+	cache        *adminCache
+}
+
+// newScopeAuditor returns nil if the engine has not been configured, in
+// which case admin privileges cannot be determined.
+func (b *backend) newScopeAuditor(ctx context.Context, s logical.Storage) (*scopeAuditor, error) {
+	conf, err := b.readConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return nil, nil
+	}
+
+	groupService, err := b.groupService(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	userService, err := b.userService(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scopeAuditor{
+		groupService: groupService,
+		userService:  userService,
+		cache:        &b.admins,
+	}, nil
+}
+
+// groupIsAdmin returns whether a group has admin privileges.
+func (a *scopeAuditor) groupIsAdmin(name string) (bool, error) {
+	if admin, ok := a.cache.get("group/" + name); ok {
+		return admin, nil
+	}
+	group, err := a.groupService.GetGroup(name)
+	if err != nil {
+		return false, err
+	}
+	admin := group != nil && group.AdminPrivileges
+	a.cache.put("group/"+name, admin)
+	return admin, nil
+}
+
+// userIsAdmin returns whether a user is an admin.
+func (a *scopeAuditor) userIsAdmin(name string) (bool, error) {
+	if admin, ok := a.cache.get("user/" + name); ok {
+		return admin, nil
+	}
+	user, err := a.userService.GetUser(name)
+	if err != nil {
+		return false, err
+	}
+	admin := user != nil && user.Admin
+	a.cache.put("user/"+name, admin)
+	return admin, nil
+}
+
+// audit finds the privileges of a role's tokens. Admin privileges are only
+// looked up when the auditor is not nil.
+func (a *scopeAuditor) audit(role *roleConfig) (*roleScope, error) {
+	scope := &roleScope{AllGroups: strutil.StrListContains(role.MemberOfGroups, "*")}
+
+	// Project tokens are confined to the project, and transient or dynamic
+	// users are never admins
+	if a == nil || role.ProjectKey != "" {
+		return scope, nil
+	}
+
+	for _, name := range role.MemberOfGroups {
+		if name == "*" {
+			continue
+		}
+		admin, err := a.groupIsAdmin(name)
+		if err != nil {
+			return nil, err
+		}
+		if admin {
+			scope.AdminReason = fmt.Sprintf("group %s has admin privileges", name)
+			return scope, nil
+		}
+	}
+
+	if scope.AllGroups && role.Username != "" {
+		admin, err := a.userIsAdmin(role.Username)
+		if err != nil {
+			return nil, err
+		}
+		if admin {
+			scope.AdminReason = fmt.Sprintf("user %s is an admin", role.Username)
+		}
+	}
+
+	return scope, nil
+}
+
+// violations lists the ways a role breaks the policy.
+func (p *policyConfig) violations(role *roleConfig, scope *roleScope) []string {
+	var violations []string
+	if p.ForbidAllGroups && (role.AllowAllGroups || scope.AllGroups) {
+		violations = append(violations, "tokens are scoped to all groups of the user")
+	}
+	if p.ForbidAdminScopes && scope.AdminReason != "" {
+		violations = append(violations, fmt.Sprintf("tokens carry admin privileges, %s", scope.AdminReason))
+	}
+	return violations
+}
+
+// validateRolePolicy rejects a role which breaks the policy.
+func (b *backend) validateRolePolicy(ctx context.Context, s logical.Storage, role *roleConfig) (*logical.Response, error) {
+	policy, err := b.readPolicyConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var auditor *scopeAuditor
+	if policy.ForbidAdminScopes {
+		if auditor, err = b.newScopeAuditor(ctx, s); err != nil {
+			return nil, err
+		}
+		// Admin privileges cannot be ruled out without asking Artifactory
+		if auditor == nil {
+			return logical.ErrorResponse("forbid_admin_scopes is set but the engine is not configured to check the role for admin privileges"), nil
+		}
+	}
+	scope, err := auditor.audit(role)
+	switch {
+	case err == rtGroupService.ErrGroupsForbidden:
+		return logical.ErrorResponse("configured credentials cannot read groups to check the role for admin privileges"), nil
+	case err != nil:
+		return nil, fmt.Errorf("Failed to check role for admin privileges: %v", err)
+	}
+
+	if violations := policy.violations(role, scope); len(violations) > 0 {
+		return logical.ErrorResponse(fmt.Sprintf("role violates policy: %s", violations[0])), nil
+	}
+
+	return nil, nil
+}
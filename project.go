@@ -0,0 +1,48 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtProjectService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/project"
+)
+
+// Project keys are 2 to 32 lowercase alphanumeric characters and hyphens,
+// starting with a letter.
+var projectKeyRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{1,31}$`)
+
+// validateRoleProject checks that the project tokens of a role are issued
+// for exists and that the configured credentials can administer it. The
+// project can only be looked up once the engine is configured.
+func (b *backend) validateRoleProject(ctx context.Context, s logical.Storage, role *roleConfig) (*logical.Response, error) {
+	if !projectKeyRegex.MatchString(role.ProjectKey) {
+		return logical.ErrorResponse(fmt.Sprintf("invalid project_key %q", role.ProjectKey)), nil
+	}
+
+	conf, err := b.readConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if conf == nil {
+		return nil, nil
+	}
+
+	projectService, err := b.projectService(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	project, err := projectService.GetProject(role.ProjectKey)
+	switch {
+	case err == rtProjectService.ErrProjectForbidden:
+		return logical.ErrorResponse(fmt.Sprintf("configured credentials cannot issue tokens for project %q", role.ProjectKey)), nil
+	case err != nil:
+		return nil, fmt.Errorf("Failed to read project: %v", err)
+	case project == nil:
+		return logical.ErrorResponse(fmt.Sprintf("project %q does not exist", role.ProjectKey)), nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,258 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+func TestProject_Role(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifactory/api/security/groups/":
+			w.Write([]byte(`[{"name": "group"}]`))
+		case "/access/api/v1/projects/ci":
+			w.Write([]byte(`{"project_key": "ci"}`))
+		case "/access/api/v1/projects/other":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		expectation Expectation
+		config      map[string]interface{}
+		role        map[string]interface{}
+	}{
+		{
+			ExpectedToSucceed,
+			map[string]interface{}{"api_key": "abc123"},
+			map[string]interface{}{"member_of_groups": "group", "project_key": "ci"},
+		},
+		{
+			FailWithLogicalError, // Project does not exist
+			map[string]interface{}{"api_key": "abc123"},
+			map[string]interface{}{"member_of_groups": "group", "project_key": "missing"},
+		},
+		{
+			FailWithLogicalError, // Credentials cannot administer the project
+			map[string]interface{}{"api_key": "abc123"},
+			map[string]interface{}{"member_of_groups": "group", "project_key": "other"},
+		},
+		{
+			ExpectedToSucceed, // Defaults to the project of the credentials
+			map[string]interface{}{"access_token": "abc123", "project_key": "ci"},
+			map[string]interface{}{"member_of_groups": "group"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"access_token": "abc123", "project_key": "ci"},
+			map[string]interface{}{"member_of_groups": "group", "project_key": "other"},
+		},
+		{
+			FailWithLogicalError,
+			map[string]interface{}{"access_token": "abc123", "project_key": "ci"},
+			map[string]interface{}{"member_of_groups": "group", "user_mode": "dynamic"},
+		},
+	}
+
+	for _, test := range tests {
+		b, storage := newBackend(t)
+
+		config := map[string]interface{}{
+			"address":    ts.URL + "/artifactory/",
+			"tls_verify": false,
+		}
+		for k, v := range test.config {
+			config[k] = v
+		}
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data:      config,
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test",
+			Storage:   storage,
+			Data:      test.role,
+		})
+		assertLogicalResponse(t, test.expectation, err, resp)
+	}
+}
+
+func TestProject_Token(t *testing.T) {
+	var revokedPath string
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/artifactory/api/security/groups/":
+			w.Write([]byte(`[{"name": "group"}]`))
+		case r.URL.Path == "/access/api/v1/projects/ci":
+			w.Write([]byte(`{"project_key": "ci"}`))
+		case r.URL.Path == "/access/api/v1/tokens" && r.Method == http.MethodPost:
+			var req struct {
+				ProjectKey string `json:"project_key"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Unable to decode token request: %v\n", err)
+			}
+			if req.ProjectKey != "ci" {
+				t.Fatalf("Expected token to be requested for project ci, got: %q\n", req.ProjectKey)
+			}
+			body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+				TokenID:     "token-1",
+				AccessToken: "abc123",
+				ExpiresIn:   3600,
+				Scope:       "applied-permissions/groups:group",
+				TokenType:   "Bearer",
+			})
+			if err != nil {
+				t.Fatal("Encoding mock HTTP response failed!")
+			}
+			w.Write(body)
+		case r.Method == http.MethodDelete:
+			revokedPath = r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Fatalf("Unexpected request: %s %s\n", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":      ts.URL + "/artifactory/",
+			"access_token": "abc123",
+			"project_key":  "ci",
+			"tls_verify":   false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"member_of_groups": "group"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "token/test",
+		Storage:   storage,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	secret, data := resp.Secret, resp.Data
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    secret,
+		Data:      data,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if revokedPath != "/access/api/v1/tokens/token-1" {
+		t.Fatalf("Expected token-1 to be revoked through the Access API, got: %s\n", revokedPath)
+	}
+
+	// Leases issued before access_api was recorded only carry the project key
+	revokedPath = ""
+	delete(secret.InternalData, "access_api")
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    secret,
+		Data:      data,
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	if revokedPath != "/access/api/v1/tokens/token-1" {
+		t.Fatalf("Expected project token-1 to be revoked through the Access API, got: %s\n", revokedPath)
+	}
+}
+
+func TestProject_RoleUpdate(t *testing.T) {
+	lookups := 0
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifactory/api/security/groups/":
+			w.Write([]byte(`[{"name": "group"}]`))
+		case "/access/api/v1/projects/ci":
+			lookups++
+			w.Write([]byte(`{"project_key": "ci"}`))
+		case "/access/api/v1/projects/other":
+			lookups++
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	// The project cannot be looked up before the engine is configured
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"member_of_groups": "group", "project_key": "ci"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"address":    ts.URL + "/artifactory/",
+			"api_key":    "abc123",
+			"tls_verify": false,
+		},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+	// Updates leaving the project unchanged do not look it up
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"description": "CI builds"},
+	})
+	assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+	if lookups != 0 {
+		t.Fatalf("Expected no project lookups for an unchanged project_key, got %d\n", lookups)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/test",
+		Storage:   storage,
+		Data:      map[string]interface{}{"project_key": "other"},
+	})
+	assertLogicalResponse(t, FailWithLogicalError, err, resp)
+	if lookups != 1 {
+		t.Fatalf("Expected a changed project_key to be looked up, got %d lookups\n", lookups)
+	}
+}
@@ -0,0 +1,125 @@
+package artifactory
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Changes recorded in the history of a role
+const (
+	roleChangeCreate   = "create"
+	roleChangeUpdate   = "update"
+	roleChangeImport   = "import"
+	roleChangeRollback = "rollback"
+	roleChangeDelete   = "delete"
+)
+
+// roleVersion is a change to a role. The history of a role is kept apart
+// from the role so that it survives the role being deleted, and versions
+// keep increasing if the role is created again.
+type roleVersion struct {
+	Version   int       `json:"version"`
+	Time      time.Time `json:"time"`
+	EntityID  string    `json:"entity_id"`
+	Operation string    `json:"operation"`
+
+	// The role as written, nil if it was deleted
+	Role *roleConfig `json:"role,omitempty"`
+}
+
+func roleHistoryKey(name string) string {
+	return "role-history/" + name
+}
+
+// readRoleHistory returns the versions of a role, oldest first.
+func readRoleHistory(ctx context.Context, s logical.Storage, name string) ([]*roleVersion, error) {
+	raw, err := s.Get(ctx, roleHistoryKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	var history []*roleVersion
+	if err := raw.DecodeJSON(&history); err != nil {
+		return nil, err
+	}
+
+	// Versions are stored as the role was, so may predate the current
+	// storage version
+	for _, version := range history {
+		if version.Role == nil {
+			continue
+		}
+		entry, err := logical.StorageEntryJSON(roleHistoryKey(name), version.Role)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := upgradeRole(entry, version.Role); err != nil {
+			return nil, err
+		}
+	}
+
+	return history, nil
+}
+
+func writeRoleHistory(ctx context.Context, s logical.Storage, name string, history []*roleVersion) error {
+	if len(history) == 0 {
+		return s.Delete(ctx, roleHistoryKey(name))
+	}
+
+	entry, err := logical.StorageEntryJSON(roleHistoryKey(name), history)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// roleLock returns the lock serialising changes to the named role.
+func (b *backend) roleLock(name string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.roleLocks, name)
+}
+
+// recordRoleVersion writes role as the next version of the named role, or
+// deletes the role if it is nil, and adds the change to its history. The
+// caller must hold the role's lock.
+func (b *backend) recordRoleVersion(ctx context.Context, s logical.Storage, name string, role *roleConfig, entityID, operation string) error {
+	conf, err := b.readHousekeepingConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	history, err := readRoleHistory(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	version := 1
+	if len(history) > 0 {
+		version = history[len(history)-1].Version + 1
+	}
+
+	if role != nil {
+		role.RoleVersion = version
+		if err := writeRole(ctx, s, name, role); err != nil {
+			return err
+		}
+	} else if err := s.Delete(ctx, "role/"+name); err != nil {
+		return err
+	}
+
+	history = append(history, &roleVersion{
+		Version:   version,
+		Time:      time.Now().UTC(),
+		EntityID:  entityID,
+		Operation: operation,
+		Role:      role,
+	})
+	if len(history) > conf.RoleHistoryVersions {
+		history = history[len(history)-conf.RoleHistoryVersions:]
+	}
+	return writeRoleHistory(ctx, s, name, history)
+}
@@ -0,0 +1,83 @@
+package artifactory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+const walAccessTokenKind = "access_token"
+
+// walAccessToken is written before an access token is created and removed
+// once the token has been returned, so that tokens orphaned by a failed
+// request can be found and revoked.
+type walAccessToken struct {
+	RoleName         string
+	Username         string
+	Transient        bool
+	DynamicUser      bool
+	PermissionTarget string
+	DynamicGroup     string
+	ProjectKey       string
+	AccessApi        bool
+
+	GroupPermissionTargets []string
+}
+
+func (b *backend) walRollback(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
+	switch kind {
+	case walAccessTokenKind:
+		return b.accessTokenRollback(ctx, req, data)
+	default:
+		return fmt.Errorf("unknown type to rollback")
+	}
+}
+
+func (b *backend) accessTokenRollback(ctx context.Context, req *logical.Request, data interface{}) error {
+	var entry walAccessToken
+	if err := mapstructure.Decode(data, &entry); err != nil {
+		return err
+	}
+
+	// Tokens can only be attributed to the failed request by their subject
+	// when the username was generated for that request. A fixed username is
+	// shared by every lease of the role, so revoking by subject would also
+	// revoke tokens which were successfully handed out.
+	if !entry.Transient {
+		b.Logger().Warn("unable to identify orphaned access token for non-transient user, skipping rollback",
+			"role", entry.RoleName, "username", entry.Username)
+		return nil
+	}
+
+	tokenService, err := b.tokenService(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	tokens, err := tokenService.GetTokens()
+	if err != nil {
+		return fmt.Errorf("Failed to list access tokens: %v", err)
+	}
+
+	for _, token := range tokens.Tokens {
+		if token.Username() != entry.Username {
+			continue
+		}
+		err := tokenService.RevokeToken(&rtTokenService.RevokeTokenRequest{TokenID: token.TokenID, AccessApi: entry.AccessApi || entry.ProjectKey != ""})
+		if err != nil {
+			return fmt.Errorf("Failed to revoke token %s: %v", token.TokenID, err)
+		}
+	}
+
+	return b.deleteLeaseResources(ctx, req.Storage, &leaseResources{
+		Username:         entry.Username,
+		DynamicUser:      entry.DynamicUser,
+		PermissionTarget: entry.PermissionTarget,
+		DynamicGroup:     entry.DynamicGroup,
+
+		GroupPermissionTargets: entry.GroupPermissionTargets,
+	})
+}
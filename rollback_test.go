@@ -0,0 +1,189 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	rtTokenService "github.com/jsok/vault-plugin-secrets-artifactory/pkg/token"
+)
+
+func TestRollback_AccessToken(t *testing.T) {
+	tests := []struct {
+		entry           *walAccessToken
+		expectedRevoked []string
+		expectedDeleted []string
+	}{
+		{
+			&walAccessToken{RoleName: "test", Username: "vault-test-1", Transient: true},
+			[]string{"token-1"},
+			nil,
+		},
+		{
+			&walAccessToken{RoleName: "test", Username: "user", Transient: false},
+			nil,
+			nil,
+		},
+		{
+			&walAccessToken{RoleName: "test", Username: "vault-test-1", Transient: true, DynamicUser: true},
+			[]string{"token-1"},
+			[]string{"vault-test-1"},
+		},
+	}
+
+	for _, test := range tests {
+		var revoked, deleted []string
+
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/security/token":
+				body, err := json.Marshal(&rtTokenService.GetTokensResponse{
+					Tokens: []rtTokenService.TokenInfo{
+						{TokenID: "token-1", Subject: "jfrt@01/users/vault-test-1"},
+						{TokenID: "token-2", Subject: "jfrt@01/users/user"},
+					},
+				})
+				if err != nil {
+					t.Fatal("Encoding mock HTTP response failed!")
+				}
+				w.Write(body)
+			case "/api/security/token/revoke":
+				revoked = append(revoked, r.FormValue("token_id"))
+				w.WriteHeader(http.StatusOK)
+			case "/api/security/users/vault-test-1":
+				if r.Method != http.MethodDelete {
+					t.Fatalf("Expected DELETE but got request with method: %s\n", r.Method)
+				}
+				deleted = append(deleted, "vault-test-1")
+				w.WriteHeader(http.StatusOK)
+			default:
+				t.Fatalf("Unexpected request path: %s\n", r.URL.Path)
+			}
+		}))
+		defer ts.Close()
+
+		b, storage := newBackend(t)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"address":    ts.URL + "/",
+				"api_key":    "abc123",
+				"tls_verify": false,
+			},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		if _, err := framework.PutWAL(context.Background(), storage, walAccessTokenKind, test.entry); err != nil {
+			t.Fatalf("Failed to write WAL entry: %v\n", err)
+		}
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RollbackOperation,
+			Storage:   storage,
+			Data:      map[string]interface{}{"immediate": true},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		if len(revoked) != len(test.expectedRevoked) {
+			t.Fatalf("Expected tokens %v to be revoked, got: %v\n", test.expectedRevoked, revoked)
+		}
+		for i := range revoked {
+			if revoked[i] != test.expectedRevoked[i] {
+				t.Fatalf("Expected tokens %v to be revoked, got: %v\n", test.expectedRevoked, revoked)
+			}
+		}
+
+		if len(deleted) != len(test.expectedDeleted) {
+			t.Fatalf("Expected users %v to be deleted, got: %v\n", test.expectedDeleted, deleted)
+		}
+
+		keys, err := framework.ListWAL(context.Background(), storage)
+		if err != nil {
+			t.Fatalf("Failed to list WAL entries: %v\n", err)
+		}
+		if len(keys) != 0 {
+			t.Fatalf("Expected WAL entry to be removed after rollback, got: %v\n", keys)
+		}
+	}
+}
+
+func TestRollback_TokenReadCommitsWAL(t *testing.T) {
+	tests := []struct {
+		expectation  Expectation
+		expectedWALs int
+		handler      http.HandlerFunc
+	}{
+		{
+			ExpectedToSucceed,
+			0,
+			func(w http.ResponseWriter, r *http.Request) {
+				body, err := json.Marshal(&rtTokenService.CreateTokenResponse{
+					AccessToken: "abc123",
+					ExpiresIn:   3600,
+					TokenType:   "Bearer",
+				})
+				if err != nil {
+					t.Fatal("Encoding mock HTTP response failed!")
+				}
+				w.Write(body)
+			},
+		},
+		{
+			FailWithError,
+			1,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewTLSServer(test.handler)
+		defer ts.Close()
+
+		b, storage := newBackend(t)
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"address":    ts.URL + "/",
+				"api_key":    "abc123",
+				"tls_verify": false,
+			},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "roles/test",
+			Storage:   storage,
+			Data:      map[string]interface{}{"member_of_groups": "group", "skip_validation": true},
+		})
+		assertLogicalResponse(t, ExpectedToSucceed, err, resp)
+
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "token/test",
+			Storage:   storage,
+		})
+		assertLogicalResponse(t, test.expectation, err, resp)
+
+		keys, err := framework.ListWAL(context.Background(), storage)
+		if err != nil {
+			t.Fatalf("Failed to list WAL entries: %v\n", err)
+		}
+		if len(keys) != test.expectedWALs {
+			t.Fatalf("Expected %d WAL entries, got: %v\n", test.expectedWALs, keys)
+		}
+	}
+}
@@ -28,17 +28,61 @@ func secretAccessToken(b *backend) *framework.Secret {
 func (b *backend) secretAccessTokenRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	accessToken := d.Get("access_token").(string)
 
-	client, rtDetails, err := b.rtClient(ctx, req.Storage)
-	if client == nil || rtDetails == nil {
-		return nil, fmt.Errorf("Failed to create Artifactory client: %v\n", err)
+	tokenService, err := b.tokenService(ctx, req.Storage)
+	if err != nil {
+		return nil, err
 	}
 
-	tokenService := rtTokenService.NewAccessTokenService(client)
-	tokenService.SetArtifactoryDetails(rtDetails)
-	err = tokenService.RevokeToken(&rtTokenService.RevokeTokenRequest{Token: accessToken})
+	revokeReq := &rtTokenService.RevokeTokenRequest{Token: accessToken}
+	// Leases of project tokens predating access_api only record the project
+	accessApi, _ := req.Secret.InternalData["access_api"].(bool)
+	projectKey, _ := req.Secret.InternalData["project_key"].(string)
+	if accessApi || projectKey != "" {
+		revokeReq.TokenID, _ = req.Secret.InternalData["token_id"].(string)
+		revokeReq.AccessApi = true
+	}
+	err = tokenService.RevokeToken(revokeReq)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to revoke token:\n%v\n", err)
 	}
+	b.Logger().Info("revoked access token",
+		"role", req.Secret.InternalData["role_name"],
+		"username", req.Secret.InternalData["username"],
+		"token_id", req.Secret.InternalData["token_id"])
+
+	resources := &leaseResources{}
+	resources.Username, _ = req.Secret.InternalData["username"].(string)
+	resources.DynamicUser, _ = req.Secret.InternalData["dynamic_user"].(bool)
+	resources.PermissionTarget, _ = req.Secret.InternalData["permission_target"].(string)
+	resources.DynamicGroup, _ = req.Secret.InternalData["dynamic_group"].(string)
+	resources.GroupPermissionTargets = internalDataStrings(req.Secret.InternalData["group_permission_targets"])
+	if err := b.deleteLeaseResources(ctx, req.Storage, resources); err != nil {
+		return nil, err
+	}
+
+	if issuanceID, ok := req.Secret.InternalData["issuance_id"].(string); ok {
+		if err := deleteIssuanceRecord(ctx, req.Storage, issuanceID); err != nil {
+			return nil, err
+		}
+	}
 
 	return nil, nil
 }
+
+// internalDataStrings returns a list from the lease InternalData, which is
+// decoded from JSON as a list of interfaces when read back from storage.
+func internalDataStrings(raw interface{}) []string {
+	switch values := raw.(type) {
+	case []string:
+		return values
+	case []interface{}:
+		strs := make([]string, 0, len(values))
+		for _, value := range values {
+			if str, ok := value.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package artifactory
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Storage versions of the role and config entries. Bump these and add an
+// upgrade step below whenever the stored representation changes.
+const (
+	roleStorageVersion   = 3
+	configStorageVersion = 1
+)
+
+// initialize upgrades stored entries to the current storage version when the
+// plugin is mounted. Entries are also upgraded as they are read, so nodes
+// which cannot write to storage still see the current representation.
+func (b *backend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	replicationState := b.System().ReplicationState()
+	if replicationState.HasState(consts.ReplicationPerformanceStandby) ||
+		(replicationState.HasState(consts.ReplicationPerformanceSecondary) && !b.System().LocalMount()) {
+		return nil
+	}
+
+	if err := b.upgradeStoredConfig(ctx, req.Storage); err != nil {
+		return err
+	}
+	return b.upgradeStoredRoles(ctx, req.Storage)
+}
+
+func (b *backend) upgradeStoredConfig(ctx context.Context, s logical.Storage) error {
+	raw, err := s.Get(ctx, "config")
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		return nil
+	}
+
+	conf := &accessConfig{}
+	if err := raw.DecodeJSON(conf); err != nil {
+		return err
+	}
+	if !upgradeConfig(conf) {
+		return nil
+	}
+
+	entry, err := logical.StorageEntryJSON("config", conf)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	b.Logger().Info("upgraded config storage", "version", conf.Version)
+	return nil
+}
+
+func (b *backend) upgradeStoredRoles(ctx context.Context, s logical.Storage) error {
+	names, err := listRoleNames(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		// Roles written before these names were reserved are shadowed by
+		// other endpoints, but can still issue tokens
+		if reason := reservedRoleName(name); reason != "" {
+			b.Logger().Warn("role can no longer be read, updated or deleted through roles/ but still issues tokens, import it under another name to manage it",
+				"role", name, "reason", reason)
+		}
+
+		raw, err := s.Get(ctx, "role/"+name)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+
+		role := new(roleConfig)
+		if err := raw.DecodeJSON(role); err != nil {
+			return err
+		}
+		upgraded, err := upgradeRole(raw, role)
+		if err != nil {
+			return err
+		}
+		if !upgraded {
+			continue
+		}
+
+		if err := writeRole(ctx, s, name, role); err != nil {
+			return err
+		}
+		b.Logger().Info("upgraded role storage", "role", name, "version", role.Version)
+	}
+
+	return nil
+}
+
+// upgradeConfig brings a config decoded from storage up to the current
+// storage version, returning whether it was changed.
+func upgradeConfig(conf *accessConfig) bool {
+	if conf.Version >= configStorageVersion {
+		return false
+	}
+
+	// Version 0 differs only by the missing version field
+	conf.Version = configStorageVersion
+	return true
+}
+
+// upgradeRole brings a role decoded from storage up to the current storage
+// version, returning whether it was changed.
+func upgradeRole(raw *logical.StorageEntry, role *roleConfig) (bool, error) {
+	if role.Version >= roleStorageVersion {
+		return false, nil
+	}
+
+	if role.Version < 1 {
+		// Version 0 stored the TTL under the "lease" key
+		var legacy struct {
+			TTL time.Duration `json:"lease"`
+		}
+		if err := raw.DecodeJSON(&legacy); err != nil {
+			return false, err
+		}
+		role.TTL = legacy.TTL
+	}
+	if role.Version < 2 && role.UserMode == "" {
+		// Roles predating user_mode always used transient users
+		role.UserMode = userModeTransient
+	}
+	if role.Version < 3 && strutil.StrListContains(role.MemberOfGroups, "*") {
+		// Roles predating allow_all_groups used * whenever no groups were set
+		role.AllowAllGroups = true
+	}
+
+	role.Version = roleStorageVersion
+	return true, nil
+}
@@ -0,0 +1,139 @@
+package artifactory
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestUpgrade_Initialize(t *testing.T) {
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	// Entries as written before storage versioning was introduced
+	legacy := []*logical.StorageEntry{
+		{
+			Key:   "config",
+			Value: []byte(`{"address":"https://example.com/artifactory","api_key":"abc123","username":"","password":"","tls_verify":true}`),
+		},
+		{
+			Key:   "role/test",
+			Value: []byte(`{"username":"user","member_of_groups":["group"],"lease":36000000000000}`),
+		},
+	}
+	for _, entry := range legacy {
+		if err := storage.Put(ctx, entry); err != nil {
+			t.Fatalf("Failed to write legacy entry: %v\n", err)
+		}
+	}
+
+	// Legacy roles are upgraded when read
+	role, err := readRole(ctx, storage, "test")
+	if err != nil {
+		t.Fatalf("Failed to read legacy role: %v\n", err)
+	}
+	if role.TTL != 10*time.Hour {
+		t.Fatalf("Expected legacy TTL to be read, got: %v\n", role.TTL)
+	}
+
+	if err := b.Initialize(ctx, &logical.InitializationRequest{Storage: storage}); err != nil {
+		t.Fatalf("Failed to initialize backend: %v\n", err)
+	}
+
+	raw, err := storage.Get(ctx, "role/test")
+	if err != nil {
+		t.Fatalf("Failed to read role entry: %v\n", err)
+	}
+	stored := map[string]interface{}{}
+	if err := raw.DecodeJSON(&stored); err != nil {
+		t.Fatalf("Failed to decode role entry: %v\n", err)
+	}
+	if _, ok := stored["lease"]; ok {
+		t.Fatalf("Expected legacy lease key to be removed, got: %v\n", stored)
+	}
+	if stored["version"].(json.Number).String() != strconv.Itoa(roleStorageVersion) {
+		t.Fatalf("Expected role to be stored at version %d, got: %v\n", roleStorageVersion, stored)
+	}
+
+	role, err = readRole(ctx, storage, "test")
+	if err != nil {
+		t.Fatalf("Failed to read upgraded role: %v\n", err)
+	}
+	if role.TTL != 10*time.Hour || role.Username != "user" || role.UserMode != userModeTransient {
+		t.Fatalf("Role fields not preserved by upgrade, got: %#v\n", role)
+	}
+
+	raw, err = storage.Get(ctx, "config")
+	if err != nil {
+		t.Fatalf("Failed to read config entry: %v\n", err)
+	}
+	conf := &accessConfig{}
+	if err := raw.DecodeJSON(conf); err != nil {
+		t.Fatalf("Failed to decode config entry: %v\n", err)
+	}
+	if conf.Version != configStorageVersion || conf.ApiKey != "abc123" {
+		t.Fatalf("Config not upgraded, got: %#v\n", conf)
+	}
+}
+
+func TestUpgrade_AllowAllGroups(t *testing.T) {
+	b, storage := newBackend(t)
+	ctx := context.Background()
+
+	// Roles written before allow_all_groups stored * for user scoped tokens
+	legacy := []*logical.StorageEntry{
+		{
+			Key:   "role/user-scoped",
+			Value: []byte(`{"version":2,"username":"user","user_mode":"transient","member_of_groups":["*"]}`),
+		},
+		{
+			Key:   "role/team/user-scoped",
+			Value: []byte(`{"version":2,"username":"user","user_mode":"transient","member_of_groups":["*"]}`),
+		},
+		{
+			Key:   "role/group-scoped",
+			Value: []byte(`{"version":2,"username":"user","user_mode":"transient","member_of_groups":["group"]}`),
+		},
+	}
+	for _, entry := range legacy {
+		if err := storage.Put(ctx, entry); err != nil {
+			t.Fatalf("Failed to write legacy entry: %v\n", err)
+		}
+	}
+
+	role, err := readRole(ctx, storage, "user-scoped")
+	if err != nil {
+		t.Fatalf("Failed to read legacy role: %v\n", err)
+	}
+	if !role.AllowAllGroups {
+		t.Fatalf("Expected user scoped role to allow all groups, got: %#v\n", role)
+	}
+
+	// Roles within namespaces are upgraded on initialize
+	if err := b.Initialize(ctx, &logical.InitializationRequest{Storage: storage}); err != nil {
+		t.Fatalf("Failed to initialize backend: %v\n", err)
+	}
+	raw, err := storage.Get(ctx, "role/team/user-scoped")
+	if err != nil {
+		t.Fatalf("Failed to read role entry: %v\n", err)
+	}
+	stored := &roleConfig{}
+	if err := raw.DecodeJSON(stored); err != nil {
+		t.Fatalf("Failed to decode role entry: %v\n", err)
+	}
+	if stored.Version != roleStorageVersion || !stored.AllowAllGroups {
+		t.Fatalf("Expected namespaced role to be upgraded, got: %#v\n", stored)
+	}
+
+	role, err = readRole(ctx, storage, "group-scoped")
+	if err != nil {
+		t.Fatalf("Failed to read legacy role: %v\n", err)
+	}
+	if role.AllowAllGroups {
+		t.Fatalf("Expected group scoped role not to allow all groups, got: %#v\n", role)
+	}
+}